@@ -1,5 +1,6 @@
 from __future__ import annotations
 
+import hashlib
 import os
 import sys
 import tempfile
@@ -21,11 +22,14 @@ DET_SIZE_TUPLE: Tuple[int, int] = tuple(int(x) for x in DET_SIZE.split(",", 1))
 
 GPU_ID = int(os.getenv("GPU_ID", "-1"))
 
+MODEL_NAME = "buffalo_l"
+
 # ---------------------------
 # Model root preparation
 # ---------------------------
 
 _analyzer: Optional[FaceAnalysis] = None
+_model_info: Dict[str, Any] = {}
 
 def _load_models() -> FaceAnalysis:
     model_dir = snapshot_download(
@@ -34,7 +38,7 @@ def _load_models() -> FaceAnalysis:
     )
 
     analyzer = FaceAnalysis(
-        name="buffalo_l",
+        name=MODEL_NAME,
         root=MODEL_DIR,
         providers=PROVIDERS,
     )
@@ -45,6 +49,32 @@ def _load_models() -> FaceAnalysis:
     return analyzer
 
 
+def _describe_model(analyzer: FaceAnalysis) -> Dict[str, Any]:
+    """
+    Identify the recognition model, so clients can tell when embeddings
+    would no longer be comparable. The version is a fingerprint of the
+    model file, so swapping the weights changes it.
+    """
+    rec = analyzer.models["recognition"]
+
+    digest = hashlib.sha256()
+    with open(rec.model_file, "rb") as f:
+        for block in iter(lambda: f.read(1 << 20), b""):
+            digest.update(block)
+
+    dim = 512
+    output_shape = getattr(rec, "output_shape", None)
+    if output_shape is not None and len(output_shape) > 1 and isinstance(output_shape[1], int):
+        dim = output_shape[1]
+
+    return {
+        "name": f"{MODEL_NAME}/{Path(rec.model_file).name}",
+        "version": digest.hexdigest()[:16],
+        "dim": dim,
+        "normalization": "l2",
+    }
+
+
 def _decode_image(image_bytes: bytes) -> np.ndarray:
     """
     Decode input bytes into a BGR OpenCV image (H, W, 3).
@@ -82,9 +112,10 @@ APP = FastAPI()
 
 @APP.on_event("startup")
 def startup() -> None:
-    global _analyzer
+    global _analyzer, _model_info
     try:
         _analyzer = _load_models()
+        _model_info = _describe_model(_analyzer)
     except Exception as e:
         # Crash early with a clear message; container orchestrators will restart.
         print(f"[startup] failed to load models: {e}", file=sys.stderr)
@@ -138,6 +169,19 @@ async def embed_largest_face(file: UploadFile = File(...)) -> JSONResponse:
         }
     )
 
+@APP.get("/info")
+async def info() -> JSONResponse:
+    """
+    Response:
+      {
+        "name": "<model pack>/<recognition model file>",
+        "version": "<fingerprint of the model file>",
+        "dim": 512,
+        "normalization": "l2"
+      }
+    """
+    return JSONResponse(_model_info)
+
 @APP.get("/healthz")
 async def health_check() -> JSONResponse:
     return JSONResponse(
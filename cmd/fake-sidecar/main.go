@@ -0,0 +1,35 @@
+// Command fake-sidecar serves the AI sidecar's HTTP contract without
+// InsightFace, so the server and ingest can run on a developer machine. See
+// the fake package for how it answers, and for the modes -mode takes.
+package main
+
+import (
+	"flag"
+	"log"
+	"log/slog"
+	"net/http"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/ai/fake"
+)
+
+func main() {
+	addr := flag.String("addr", ":8081", "Listen address")
+	mode := flag.String("mode", fake.ModeFace, "Response mode: face, no-face, low-score or blur")
+	name := flag.String("model-name", "fake", "Model name reported by /info")
+	version := flag.String("model-version", "1", "Model version reported by /info")
+	dim := flag.Int("dim", 512, "Embedding dimensions")
+	flag.Parse()
+
+	if !fake.ValidMode(*mode) {
+		log.Fatalf("unknown mode: %s", *mode)
+	}
+	if *dim <= 0 {
+		log.Fatalf("invalid dim: %d", *dim)
+	}
+
+	info := ai.ModelInfo{Name: *name, Version: *version, Dim: *dim, Normalization: "l2"}
+
+	slog.Info("Fake sidecar listening", "address", *addr, "model", info.ID(), "mode", *mode)
+	log.Fatal(http.ListenAndServe(*addr, fake.New(*mode, info)))
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/face-match/internal/imaging"
+	"github.com/face-match/internal/service"
+	"github.com/spf13/cobra"
+)
+
+func cmdCanary(dependencies *Dependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "canary",
+		Short: "Reference images used to detect embedding drift",
+	}
+
+	var name string
+
+	cmdAdd := &cobra.Command{
+		Use:   "add <file>...",
+		Short: "Register images as canaries, named after their files unless --name is given.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name != "" && len(args) > 1 {
+				return fmt.Errorf("--name can only be used with a single file")
+			}
+
+			s := service.NewCanaryService(dependencies.Pool, dependencies.AI)
+			for _, path := range args {
+				imageBytes, err := imaging.ReadFile(path, dependencies.Config.MaxUploadSize)
+				if err != nil {
+					return err
+				}
+				canaryName := name
+				if canaryName == "" {
+					canaryName = filepath.Base(path)
+				}
+				if err := s.Register(cmd.Context(), canaryName, imageBytes); err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				fmt.Printf("Registered %s\n", canaryName)
+			}
+			return nil
+		},
+	}
+	cmdAdd.Flags().StringVar(&name, "name", "", "Canary name (defaults to the file name)")
+
+	cmdList := &cobra.Command{
+		Use:   "list",
+		Short: "List the registered canaries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s := service.NewCanaryService(dependencies.Pool, dependencies.AI)
+			canaries, err := s.List(cmd.Context())
+			if err != nil {
+				return err
+			}
+			for _, c := range canaries {
+				fmt.Printf("%d\t%s\tmodel=%d\t%s\n", c.ID, c.Name, c.ModelID, c.CreatedAt.Format("2006-01-02 15:04:05"))
+			}
+			return nil
+		},
+	}
+
+	cmdRemove := &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a canary and its check history",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s := service.NewCanaryService(dependencies.Pool, dependencies.AI)
+			return s.Remove(cmd.Context(), args[0])
+		},
+	}
+
+	var threshold float64
+
+	cmdCheck := &cobra.Command{
+		Use:   "check",
+		Short: "Re-embed the canaries and fail when any drifted beyond the threshold.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s := service.NewCanaryService(dependencies.Pool, dependencies.AI)
+			results, err := s.Check(cmd.Context(), threshold)
+			for _, r := range results {
+				switch {
+				case r.Error != "":
+					fmt.Printf("FAIL\t%s\t%s\n", r.Name, r.Error)
+				case r.Drifted:
+					fmt.Printf("DRIFT\t%s\t%.6f\n", r.Name, r.Drift)
+				default:
+					fmt.Printf("OK\t%s\t%.6f\n", r.Name, r.Drift)
+				}
+			}
+			return err
+		},
+	}
+	cmdCheck.Flags().Float64Var(&threshold, "threshold", service.DefaultCanaryThreshold, "Largest acceptable cosine distance")
+
+	cmd.AddCommand(cmdAdd, cmdList, cmdRemove, cmdCheck)
+	return cmd
+}
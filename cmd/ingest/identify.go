@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/face-match/internal/service"
+	"github.com/face-match/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func cmdIdentify(dependencies *Dependencies) *cobra.Command {
+	var categories []string
+	var format string
+	var output string
+	var concurrency int
+
+	cmd := &cobra.Command{
+		Use:   "identify <dir>",
+		Short: "Search for the faces in every image of a folder.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "csv" && format != "json" {
+				return fmt.Errorf("unknown format: %s", format)
+			}
+
+			if err := verifyModel(cmd, dependencies); err != nil {
+				return err
+			}
+
+			categoryIDs, err := resolveCategoryIDs(cmd, dependencies, categories)
+			if err != nil {
+				return err
+			}
+
+			images, err := service.ReadImageDir(args[0], dependencies.Config.MaxUploadSize)
+			if err != nil {
+				return err
+			}
+
+			s := service.NewSearchService(dependencies.Config, dependencies.Pool, dependencies.AI)
+			results := s.SearchBatch(cmd.Context(), categoryIDs, images, concurrency)
+
+			var out io.Writer = os.Stdout
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer func() { _ = f.Close() }()
+				out = f
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(out)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(results)
+			}
+			return writeIdentifyCsv(out, results)
+		},
+	}
+	cmd.Flags().StringSliceVar(&categories, "category", nil, "Category to search (repeatable; default all)")
+	cmd.Flags().StringVar(&format, "format", "csv", "Output format: csv or json")
+	cmd.Flags().StringVar(&output, "output", "", "Output file (default stdout)")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 4, "Number of images embedded at once")
+
+	return cmd
+}
+
+func resolveCategoryIDs(cmd *cobra.Command, dependencies *Dependencies, categories []string) ([]int64, error) {
+	cs := store.NewCategoryStore(dependencies.Pool)
+
+	if len(categories) == 0 {
+		all, err := cs.List(cmd.Context())
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]int64, 0, len(all))
+		for _, c := range all {
+			ids = append(ids, c.ID)
+		}
+		return ids, nil
+	}
+
+	ids := make([]int64, 0, len(categories))
+	for _, category := range categories {
+		id, err := cs.FetchId(cmd.Context(), category)
+		if err != nil {
+			return nil, fmt.Errorf("category %q: %w", category, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// writeIdentifyCsv writes one row per match, or a single row with the error
+// for images that could not be searched.
+func writeIdentifyCsv(out io.Writer, results map[string]service.BatchResult) error {
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"file", "rank", "person_id", "display_name", "disambiguation_tag", "category_id", "similarity_score", "error"})
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		result := results[name]
+		if result.Error != "" || len(result.Results) == 0 {
+			_ = w.Write([]string{name, "", "", "", "", "", "", result.Error})
+			continue
+		}
+		for i, r := range result.Results {
+			_ = w.Write([]string{
+				name,
+				strconv.Itoa(i + 1),
+				strconv.FormatInt(r.PersonID, 10),
+				r.DisplayName,
+				r.DisambiguationTag,
+				strconv.FormatInt(r.CategoryID, 10),
+				strconv.FormatFloat(float64(r.SimilarityScore), 'f', 4, 32),
+				"",
+			})
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
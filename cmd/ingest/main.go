@@ -4,46 +4,84 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
-	"path/filepath"
 
+	"github.com/face-match/internal/ai"
 	"github.com/face-match/internal/app"
+	"github.com/face-match/internal/logging"
+	"github.com/face-match/internal/metrics"
 	"github.com/face-match/internal/service"
 	"github.com/face-match/internal/store"
+	"github.com/face-match/internal/tracing"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/spf13/cobra"
 )
 
+// Commands annotated with annotationNoDatabase don't get a database pool,
+// and don't need database_url.
+const annotationNoDatabase = "no-database"
+
 type Dependencies struct {
 	Config *app.Config
 	Pool   *pgxpool.Pool
+	AI     *ai.Client
 }
 
 func main() {
-	config := &app.Config{
-		AIEndpoint:  os.Getenv("AI_ENDPOINT"),
-		DatabaseUrl: os.Getenv("DATABASE_URL"),
-		DataRoot:    os.Getenv("DATA_ROOT"),
-	}
-	config.InputPath = filepath.Join(config.DataRoot, "/ingest/input")
-	config.FinishedPath = filepath.Join(config.DataRoot, "/ingest/finished")
-	config.ThumbsPath = filepath.Join(config.DataRoot, "/images/thumbs")
+	dependencies := &Dependencies{}
 
-	dependencies := &Dependencies{
-		Config: config,
-	}
+	var metricsTextfile string
+	shutdownTracing := func(context.Context) error { return nil }
 
 	var rootCmd = &cobra.Command{
 		Use:   "ingest",
 		Short: "Ingestion tool for the face match website.",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			if dependencies.Pool == nil {
+			needsDatabase := cmd.Annotations[annotationNoDatabase] == ""
+			var required []string
+			if needsDatabase {
+				required = append(required, "database_url")
+			}
+			config, err := app.LoadConfig(cmd.Flags(), required...)
+			if err != nil {
+				return err
+			}
+			if app.PrintRequested(cmd.Flags()) {
+				if err := app.PrintConfig(os.Stdout, config); err != nil {
+					return err
+				}
+				os.Exit(0)
+			}
+			dependencies.Config = config
+
+			if err := logging.Setup(os.Stderr, config.LogFormat, config.LogLevel); err != nil {
+				return err
+			}
+
+			shutdownTracing, err = tracing.Setup(cmd.Context(), "face-match-ingest", config.OtlpEndpoint, config.TraceSampleRatio)
+			if err != nil {
+				return err
+			}
+
+			if dependencies.Pool == nil && needsDatabase {
 				pool, err := store.Open(cmd.Context(), config.DatabaseUrl)
 				if err != nil {
 					return err
 				}
 				dependencies.Pool = pool
 			}
+			if dependencies.AI == nil && config.AIEndpoint != "" {
+				client, err := ai.NewClient(config.AIEndpoint)
+				if err != nil {
+					return err
+				}
+				if dependencies.Pool != nil {
+					client.UseCache(service.NewEmbeddingCache(dependencies.Pool))
+				}
+				client.Start(cmd.Context())
+				dependencies.AI = client
+			}
 			return nil
 		},
 		PersistentPostRun: func(cmd *cobra.Command, args []string) {
@@ -54,16 +92,31 @@ func main() {
 		},
 	}
 
-	rootCmd.PersistentFlags().StringVar(&config.DatabaseUrl, "database-url", config.DatabaseUrl, "Database URL")
-	rootCmd.PersistentFlags().StringVar(&config.DataRoot, "data-root", config.DataRoot, "Data root directory")
+	app.RegisterFlags(rootCmd.PersistentFlags())
+	rootCmd.PersistentFlags().StringVar(&metricsTextfile, "metrics-textfile", "", "Write metrics to this file for node-exporter's textfile collector when done")
 
 	rootCmd.AddCommand(cmdCategories(dependencies))
 	rootCmd.AddCommand(cmdImport(dependencies))
 	rootCmd.AddCommand(cmdSearch(dependencies))
 	rootCmd.AddCommand(cmdPerson(dependencies))
+	rootCmd.AddCommand(cmdIdentify(dependencies))
+	rootCmd.AddCommand(cmdVideo(dependencies))
+	rootCmd.AddCommand(cmdModel(dependencies))
+	rootCmd.AddCommand(cmdCanary(dependencies))
+	rootCmd.AddCommand(cmdSidecar(dependencies))
 
 	ctx := context.Background()
-	if err := rootCmd.ExecuteContext(ctx); err != nil {
+	err := rootCmd.ExecuteContext(ctx)
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Flushing traces failed", "error", err)
+	}
+	if metricsTextfile != "" {
+		// Written even when the command failed, so the failure is visible
+		if err := metrics.WriteTextfile(metricsTextfile); err != nil {
+			slog.Error("Writing metrics failed", "path", metricsTextfile, "error", err)
+		}
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -93,7 +146,7 @@ func cmdImport(dependencies *Dependencies) *cobra.Command {
 		Use:   "import",
 		Short: "Import all images in the ingest input folder for a single category.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			s := service.NewImportService(dependencies.Config, dependencies.Pool)
+			s := service.NewImportService(dependencies.Config, dependencies.Pool, dependencies.AI)
 			if err := s.Import(cmd.Context(), category); err != nil {
 				return err
 			}
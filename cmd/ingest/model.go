@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/face-match/internal/service"
+	"github.com/face-match/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func cmdModel(dependencies *Dependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "model",
+		Short: "Embedding model maintenance",
+	}
+
+	cmdShow := &cobra.Command{
+		Use:   "show",
+		Short: "Show the sidecar's model and the recorded models",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dependencies.AI != nil {
+				info, err := dependencies.AI.Handshake(cmd.Context())
+				if err != nil {
+					fmt.Printf("sidecar\terror: %v\n", err)
+				} else {
+					fmt.Printf("sidecar\t%s\tdim=%d\tnormalization=%s\n", info.ID(), info.Dim, info.Normalization)
+				}
+			}
+
+			ms := store.NewModelStore(dependencies.Pool)
+			models, err := ms.List(cmd.Context())
+			if err != nil {
+				return err
+			}
+			for _, m := range models {
+				fmt.Printf("%d\t%s@%s\tdim=%d\tnormalization=%s\tactive=%v\n", m.ID, m.Name, m.Version, m.Dim, m.Normalization, m.IsActive)
+			}
+			return nil
+		},
+	}
+
+	cmdActivate := &cobra.Command{
+		Use:   "activate",
+		Short: "Make the sidecar's current model the active one (stored embeddings must be re-imported).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s := service.NewModelService(dependencies.Pool, dependencies.AI)
+			model, err := s.Activate(cmd.Context())
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Activated %s@%s\n", model.Name, model.Version)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(cmdShow, cmdActivate)
+	return cmd
+}
+
+// verifyModel refuses to search with a sidecar whose embeddings aren't
+// comparable with the stored ones.
+func verifyModel(cmd *cobra.Command, dependencies *Dependencies) error {
+	_, err := service.NewModelService(dependencies.Pool, dependencies.AI).Verify(cmd.Context())
+	return err
+}
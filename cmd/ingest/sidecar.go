@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/face-match/internal/ai/contract"
+	"github.com/spf13/cobra"
+)
+
+func cmdSidecar(dependencies *Dependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sidecar",
+		Short: "AI sidecar maintenance",
+	}
+
+	var face string
+
+	cmdVerify := &cobra.Command{
+		Use:         "verify",
+		Short:       "Check that every sidecar in AI_ENDPOINT follows the HTTP contract.",
+		Annotations: map[string]string{annotationNoDatabase: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dependencies.AI == nil {
+				return fmt.Errorf("AIEndpoint is required")
+			}
+
+			var faceBytes []byte
+			if face != "" {
+				var err error
+				faceBytes, err = os.ReadFile(face)
+				if err != nil {
+					return err
+				}
+			}
+
+			failed := 0
+			for _, endpoint := range dependencies.AI.Endpoints() {
+				report := contract.Verify(cmd.Context(), endpoint, faceBytes)
+				fmt.Println(report.Endpoint)
+				for _, r := range report.Results {
+					switch {
+					case r.Skipped():
+						fmt.Printf("  SKIP\t%s\t%v\n", r.Name, r.Err)
+					case r.Err != nil:
+						fmt.Printf("  FAIL\t%s\t%v\n", r.Name, r.Err)
+					default:
+						fmt.Printf("  OK\t%s\n", r.Name)
+					}
+				}
+				if !report.Passed() {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d sidecar(s) failed the contract", failed)
+			}
+			return nil
+		},
+	}
+	cmdVerify.Flags().StringVar(&face, "face", "", "Image with one face, needed for the embedding checks")
+
+	cmd.AddCommand(cmdVerify)
+	return cmd
+}
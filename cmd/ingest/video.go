@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/face-match/internal/service"
+	"github.com/face-match/internal/video"
+	"github.com/spf13/cobra"
+)
+
+func cmdVideo(dependencies *Dependencies) *cobra.Command {
+	var categories []string
+	var interval time.Duration
+	var format string
+	var concurrency int
+
+	cmd := &cobra.Command{
+		Use:   "video <file>",
+		Short: "List who appears when in a video clip.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("unknown format: %s", format)
+			}
+
+			if err := verifyModel(cmd, dependencies); err != nil {
+				return err
+			}
+
+			categoryIDs, err := resolveCategoryIDs(cmd, dependencies, categories)
+			if err != nil {
+				return err
+			}
+
+			videoBytes, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			s := service.NewSearchService(dependencies.Config, dependencies.Pool, dependencies.AI)
+			extractor := video.NewExtractor(dependencies.Config.FrameExtractor)
+			timeline, err := s.SearchVideo(cmd.Context(), categoryIDs, extractor, videoBytes, interval, concurrency)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(timeline)
+			}
+
+			fmt.Printf("%d frame(s) sampled\n", len(timeline.Frames))
+			for _, a := range timeline.Appearances {
+				fmt.Printf("%7.2fs - %7.2fs\t%d\t%s\t%s\tframes=%d\tscore=%.2f\n",
+					a.StartSeconds, a.EndSeconds, a.PersonID, a.DisplayName, a.DisambiguationTag, a.Frames, a.BestScore)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&categories, "category", nil, "Category to search (repeatable; default all)")
+	cmd.Flags().DurationVar(&interval, "interval", time.Second, "Time between sampled frames")
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text or json")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 4, "Number of frames embedded at once")
+
+	return cmd
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/face-match/internal/service"
+	"github.com/face-match/internal/store"
+	"github.com/face-match/pkg/api"
+)
+
+// requireAdmin only lets requests bearing the configured admin token through.
+// Without one, the admin API is disabled.
+func (srv *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if srv.config.AdminToken == "" {
+			writeError(w, http.StatusForbidden, "admin_disabled", "The admin API is disabled, as no admin token is configured.")
+			return
+		}
+
+		if !bearerTokenMatches(r.Header.Get("Authorization"), srv.config.AdminToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized", "A valid admin token is required.")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// bearerTokenMatches reports whether an Authorization value carries token,
+// in constant time.
+func bearerTokenMatches(authorization string, token string) bool {
+	given, ok := strings.CutPrefix(authorization, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// handleAdminPeople finds people by a part of their name, hidden ones
+// included.
+func (srv *Server) handleAdminPeople(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "invalid_query", "Give a part of the name to look for in q.")
+		return
+	}
+
+	people, err := store.NewPersonStore(srv.pool).Search(r.Context(), query)
+	if err != nil {
+		writeServiceError(w, r, "person store", err)
+		return
+	}
+
+	out := api.PersonList{People: make([]api.Person, 0, len(people))}
+	for _, person := range people {
+		out.People = append(out.People, api.Person{
+			ID:                person.ID,
+			CategoryID:        person.CategoryId,
+			Category:          person.Category,
+			DisplayName:       person.DisplayName,
+			DisambiguationTag: person.DisambiguationTag,
+			Hidden:            person.IsHidden,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleAdminPerson hides or shows a person with PATCH, and purges them and
+// their images with DELETE.
+func (srv *Server) handleAdminPerson(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusNotFound, "not_found", "Not found.")
+		return
+	}
+	personStore := store.NewPersonStore(srv.pool)
+
+	switch r.Method {
+	case http.MethodPatch:
+		var update api.PersonUpdate
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&update); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeFormError(w, err)
+				return
+			}
+			writeError(w, http.StatusBadRequest, "invalid_body", "The body must be a JSON person update.")
+			return
+		}
+		if update.Hidden != nil {
+			if err := personStore.SetHidden(r.Context(), id, *update.Hidden); err != nil {
+				writeServiceError(w, r, "person store", err)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := personStore.Purge(r.Context(), id); err != nil {
+			writeServiceError(w, r, "person store", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+	}
+}
+
+// handleAdminCanaries re-embeds the canaries now. Drifted canaries are
+// reported in the results rather than as an error.
+func (srv *Server) handleAdminCanaries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	threshold := service.DefaultCanaryThreshold
+	if value := r.URL.Query().Get("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_query", "The threshold is not valid.")
+			return
+		}
+		threshold = parsed
+	}
+
+	results, err := service.NewCanaryService(srv.pool, srv.aiClient).Check(r.Context(), threshold)
+	if err != nil && !errors.Is(err, service.ErrCanaryDrift) {
+		writeServiceError(w, r, "canary service", err)
+		return
+	}
+
+	out := api.CanaryCheck{Threshold: threshold, Results: make([]api.CanaryResult, 0, len(results))}
+	for _, result := range results {
+		out.Results = append(out.Results, api.CanaryResult(result))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
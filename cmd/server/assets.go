@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// assetHandler serves the web UI. HTML files are templates that link to the
+// other files with {{asset "name"}}, which adds the file's content hash, so
+// those links can be cached for good while the pages themselves are always
+// revalidated.
+type assetHandler struct {
+	fsys fs.FS
+
+	// Re-reads fsys on every request, for editing the files in place.
+	dev bool
+
+	assets map[string]*asset
+}
+
+type asset struct {
+	content []byte
+	hash    string
+}
+
+func newAssetHandler(fsys fs.FS, dev bool) (*assetHandler, error) {
+	h := &assetHandler{fsys: fsys, dev: dev}
+	if !dev {
+		assets, err := loadAssets(fsys)
+		if err != nil {
+			return nil, err
+		}
+		h.assets = assets
+	}
+	return h, nil
+}
+
+func (h *assetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	assets := h.assets
+	if h.dev {
+		var err error
+		if assets, err = loadAssets(h.fsys); err != nil {
+			logger.ErrorContext(r.Context(), "Loading web assets failed", "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" || strings.HasSuffix(r.URL.Path, "/") {
+		name = path.Join(name, "index.html")
+	}
+	a, ok := assets[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("ETag", `"`+a.hash+`"`)
+	if !h.dev && r.URL.Query().Get("v") == a.hash {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(a.content))
+}
+
+// loadAssets reads every file in fsys, then renders the HTML templates with
+// the hashes of the rest.
+func loadAssets(fsys fs.FS) (map[string]*asset, error) {
+	assets := map[string]*asset{}
+	var pages []string
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		if path.Ext(name) == ".html" {
+			pages = append(pages, name)
+			return nil
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		assets[name] = newAsset(content)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("web assets: %w", err)
+	}
+
+	for _, name := range pages {
+		content, err := renderPage(fsys, name, assets)
+		if err != nil {
+			return nil, fmt.Errorf("web assets: %s: %w", name, err)
+		}
+		assets[name] = newAsset(content)
+	}
+	return assets, nil
+}
+
+func renderPage(fsys fs.FS, name string, assets map[string]*asset) ([]byte, error) {
+	source, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	funcs := template.FuncMap{
+		"asset": func(ref string) (string, error) {
+			a, ok := assets[path.Join(path.Dir(name), ref)]
+			if !ok {
+				return "", fmt.Errorf("no asset %q", ref)
+			}
+			return ref + "?v=" + a.hash, nil
+		},
+	}
+	page, err := template.New(name).Funcs(funcs).Parse(string(source))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func newAsset(content []byte) *asset {
+	sum := sha256.Sum256(content)
+	return &asset{content: content, hash: hex.EncodeToString(sum[:8])}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/imaging"
+	"github.com/face-match/internal/service"
+	"github.com/face-match/internal/video"
+	"github.com/face-match/pkg/api"
+	"github.com/jackc/pgx/v5"
+)
+
+// writeError writes an api.ErrorResponse, the body of every failed request.
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeErrorDetail(w, status, api.ErrorDetail{Code: code, Message: message})
+}
+
+func writeErrorDetail(w http.ResponseWriter, status int, detail api.ErrorDetail) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(api.ErrorResponse{Error: detail})
+}
+
+// writeServiceError maps errors returned by the services to a response.
+// Anything unexpected is logged and reported as an internal error.
+func writeServiceError(w http.ResponseWriter, r *http.Request, source string, err error) {
+	var lowQuality *ai.ErrLowQuality
+	switch {
+	case errors.As(err, &lowQuality):
+		writeErrorDetail(w, http.StatusUnprocessableEntity, api.ErrorDetail{
+			Code:      "low_quality",
+			Message:   lowQualityMessage(lowQuality.Reason),
+			Reason:    lowQuality.Reason,
+			Value:     &lowQuality.Value,
+			Threshold: &lowQuality.Threshold,
+			Quality:   toQualityReport(lowQuality.Report),
+		})
+	case errors.Is(err, ai.ErrNoFace):
+		writeError(w, http.StatusUnprocessableEntity, "no_face", "No face was found in the image.")
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "The file is not a supported image. Try a JPEG or PNG file.")
+	case errors.Is(err, imaging.ErrTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "image_too_large", fmt.Sprintf("The image is too large. Images may be at most %d pixels wide or high, and %d megapixels.", imaging.MaxDimension, imaging.MaxPixels/1_000_000))
+	case errors.Is(err, ai.ErrBadImage):
+		writeError(w, http.StatusBadRequest, "bad_image", "The image could not be read. Try a JPEG or PNG file.")
+	case errors.Is(err, video.ErrUnsupportedFormat):
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported_format", "The video format is not supported.")
+	case errors.Is(err, video.ErrTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "video_too_large", "The video has too many large frames to sample. Try a longer interval.")
+	case errors.Is(err, service.ErrInvalidWebhookUrl):
+		writeError(w, http.StatusBadRequest, "invalid_webhook_url", "The webhook URL must be an absolute http or https URL of a public host.")
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "Not found.")
+	case errors.Is(err, ai.ErrModelMismatch):
+		logger.ErrorContext(r.Context(), "Request failed", "source", source, "error", err)
+		writeError(w, http.StatusServiceUnavailable, "model_mismatch", "Face recognition is misconfigured. Please try again later.")
+	case errors.Is(err, ai.ErrSidecarUnavailable):
+		logger.ErrorContext(r.Context(), "Request failed", "source", source, "error", err)
+		writeError(w, http.StatusServiceUnavailable, "sidecar_unavailable", "Face recognition is temporarily unavailable. Please try again later.")
+	default:
+		logger.ErrorContext(r.Context(), "Request failed", "source", source, "error", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+// writeFormError reports a request body that couldn't be read, telling
+// uploads over the size limit apart from malformed ones.
+func writeFormError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("The upload is too large. Requests may be at most %d MB.", tooLarge.Limit>>20))
+	case errors.Is(err, multipart.ErrMessageTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "too_large", "The form has too many or too large fields.")
+	case errors.Is(err, service.ErrArchiveTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("The archive is too large. It may hold at most %d images, and unpack to a few times the upload limit.", service.MaxBatchImages))
+	case errors.Is(err, service.ErrTooManyImages):
+		writeError(w, http.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("The batch has too many images. Batches may hold at most %d.", service.MaxBatchImages))
+	case errors.Is(err, imaging.ErrTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "image_too_large", fmt.Sprintf("The image is too large. Images may be at most %d pixels wide or high, and %d megapixels.", imaging.MaxDimension, imaging.MaxPixels/1_000_000))
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_form", "The form could not be read.")
+	}
+}
+
+func toQualityReport(report *ai.QualityReport) *api.QualityReport {
+	if report == nil {
+		return nil
+	}
+	out := &api.QualityReport{Checks: make([]api.QualityCheck, 0, len(report.Checks))}
+	for _, check := range report.Checks {
+		out.Checks = append(out.Checks, api.QualityCheck(check))
+	}
+	return out
+}
+
+func lowQualityMessage(reason string) string {
+	switch reason {
+	case "det_score":
+		return "The face could not be detected confidently. Try a clearer, front-facing photo."
+	case "face_height", "face_size":
+		return "The face is too small. Try a closer or higher resolution photo."
+	case "sharpness":
+		return "The face is too blurry. Try a sharper photo."
+	default:
+		return "The face is not clear enough to search."
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/imaging"
+	"github.com/face-match/internal/logging"
+	"github.com/face-match/internal/service"
+	"github.com/face-match/internal/store"
+	"github.com/face-match/pkg/facematchpb"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the domain of the ErrorInfo attached to failed calls.
+const errorDomain = "face-match"
+
+var grpcLogger = logging.For("grpc")
+
+// grpcServer serves the FaceMatch gRPC service with the same services as the
+// HTTP API.
+type grpcServer struct {
+	facematchpb.UnimplementedFaceMatchServer
+	srv *Server
+}
+
+// newGrpcServer returns a gRPC server with the FaceMatch service and
+// reflection registered. Messages are limited like HTTP request bodies.
+func newGrpcServer(srv *Server) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.MaxRecvMsgSize(int(srv.config.MaxUploadSize)),
+		grpc.ChainUnaryInterceptor(grpcUnaryInterceptor),
+		grpc.ChainStreamInterceptor(grpcStreamInterceptor),
+	)
+	facematchpb.RegisterFaceMatchServer(server, &grpcServer{srv: srv})
+	reflection.Register(server)
+	return server
+}
+
+func (g *grpcServer) Search(ctx context.Context, req *facematchpb.SearchRequest) (*facematchpb.SearchResponse, error) {
+	if err := checkCategoryIDs(req.GetCategoryIds()); err != nil {
+		return nil, err
+	}
+
+	searchService := service.NewSearchService(g.srv.config, g.srv.pool, g.srv.aiClient)
+
+	var results []service.SearchResult
+	var err error
+	switch query := req.GetQuery().(type) {
+	case *facematchpb.SearchRequest_Image:
+		if len(query.Image) == 0 {
+			return nil, grpcStatus(codes.InvalidArgument, "missing_image", "The image is empty.")
+		}
+		var categoryIDs []int64
+		if categoryIDs, err = searchService.DefaultCategoryIDs(ctx, req.GetCategoryIds()); err == nil {
+			results, err = searchService.Search(ctx, categoryIDs, query.Image)
+		}
+	case *facematchpb.SearchRequest_ImageId:
+		if query.ImageId <= 0 {
+			return nil, grpcStatus(codes.InvalidArgument, "invalid_argument", "The image_id is not valid.")
+		}
+		results, err = searchService.SearchByImage(ctx, req.GetCategoryIds(), query.ImageId)
+	case *facematchpb.SearchRequest_PersonId:
+		if query.PersonId <= 0 {
+			return nil, grpcStatus(codes.InvalidArgument, "invalid_argument", "The person_id is not valid.")
+		}
+		results, err = searchService.SearchByPerson(ctx, req.GetCategoryIds(), query.PersonId)
+	default:
+		return nil, grpcStatus(codes.InvalidArgument, "missing_image", "Send an image, or give an image_id or person_id.")
+	}
+	if err != nil {
+		return nil, grpcServiceError(ctx, "search service", err)
+	}
+
+	return &facematchpb.SearchResponse{Matches: toProtoMatches(results)}, nil
+}
+
+// SearchBatch reads the whole stream before searching, so that the images
+// are searched concurrently like an HTTP batch. Like an archive, a batch
+// holds at most service.MaxBatchImages images, and its images and names
+// together are limited to the maximum upload size.
+func (g *grpcServer) SearchBatch(stream grpc.ClientStreamingServer[facematchpb.SearchBatchRequest, facematchpb.SearchBatchResponse]) error {
+	var images []service.BatchImage
+	var categoryIDs []int64
+	var size int64
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(images) == 0 {
+			if err := checkCategoryIDs(req.GetCategoryIds()); err != nil {
+				return err
+			}
+			categoryIDs = req.GetCategoryIds()
+		}
+		if len(images) == service.MaxBatchImages {
+			return grpcStatus(codes.ResourceExhausted, "too_large", fmt.Sprintf("The batch has too many images. Batches may hold at most %d.", service.MaxBatchImages))
+		}
+		size += int64(len(req.GetImage()) + len(req.GetName()))
+		if size > g.srv.config.MaxUploadSize {
+			return grpcStatus(codes.ResourceExhausted, "too_large", fmt.Sprintf("The batch is too large. Batches may be at most %d MB.", g.srv.config.MaxUploadSize>>20))
+		}
+		name := req.GetName()
+		if name == "" {
+			name = strconv.Itoa(len(images))
+		}
+		images = append(images, service.BatchImage{Name: name, Bytes: req.GetImage()})
+	}
+	if len(images) == 0 {
+		return grpcStatus(codes.InvalidArgument, "no_images", "No images were sent.")
+	}
+
+	searchService := service.NewSearchService(g.srv.config, g.srv.pool, g.srv.aiClient)
+	categoryIDs, err := searchService.DefaultCategoryIDs(stream.Context(), categoryIDs)
+	if err != nil {
+		return grpcServiceError(stream.Context(), "search service", err)
+	}
+	results := searchService.SearchBatch(stream.Context(), categoryIDs, images, g.srv.config.BatchConcurrency)
+
+	out := &facematchpb.SearchBatchResponse{Results: make(map[string]*facematchpb.BatchResult, len(results))}
+	for name, result := range results {
+		out.Results[name] = &facematchpb.BatchResult{Matches: toProtoMatches(result.Results), Error: result.Error}
+	}
+	return stream.SendAndClose(out)
+}
+
+// Enroll needs the admin token, like the HTTP admin API.
+func (g *grpcServer) Enroll(ctx context.Context, req *facematchpb.EnrollRequest) (*facematchpb.EnrollResponse, error) {
+	if err := g.checkAdmin(ctx); err != nil {
+		return nil, err
+	}
+	switch {
+	case req.GetCategory() == "":
+		return nil, grpcStatus(codes.InvalidArgument, "invalid_argument", "The category is missing.")
+	case req.GetDisplayName() == "":
+		return nil, grpcStatus(codes.InvalidArgument, "invalid_argument", "The display_name is missing.")
+	case len(req.GetImage()) == 0:
+		return nil, grpcStatus(codes.InvalidArgument, "missing_image", "The image is empty.")
+	}
+
+	importService := service.NewImportService(g.srv.config, g.srv.pool, g.srv.aiClient)
+	enrollment, err := importService.Enroll(ctx, req.GetCategory(), req.GetDisplayName(), req.GetDisambiguationTag(), req.GetImage())
+	if err != nil {
+		return nil, grpcServiceError(ctx, "import service", err)
+	}
+
+	return &facematchpb.EnrollResponse{PersonId: enrollment.PersonID, ImageId: enrollment.ImageID}, nil
+}
+
+func (g *grpcServer) ListCategories(ctx context.Context, _ *facematchpb.ListCategoriesRequest) (*facematchpb.ListCategoriesResponse, error) {
+	categories, err := store.NewCategoryStore(g.srv.pool).List(ctx)
+	if err != nil {
+		return nil, grpcServiceError(ctx, "category store", err)
+	}
+
+	out := &facematchpb.ListCategoriesResponse{Categories: make([]*facematchpb.Category, 0, len(categories))}
+	for _, category := range categories {
+		out.Categories = append(out.Categories, &facematchpb.Category{Id: category.ID, Name: category.DisplayName})
+	}
+	return out, nil
+}
+
+func (g *grpcServer) checkAdmin(ctx context.Context) error {
+	if g.srv.config.AdminToken == "" {
+		return grpcStatus(codes.PermissionDenied, "admin_disabled", "Enrolling is disabled, as no admin token is configured.")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, authorization := range md.Get("authorization") {
+		if bearerTokenMatches(authorization, g.srv.config.AdminToken) {
+			return nil
+		}
+	}
+	return grpcStatus(codes.Unauthenticated, "unauthorized", "A valid admin token is required.")
+}
+
+func checkCategoryIDs(categoryIDs []int64) error {
+	for _, id := range categoryIDs {
+		if id <= 0 {
+			return grpcStatus(codes.InvalidArgument, "invalid_argument", "The category_ids are not valid.")
+		}
+	}
+	return nil
+}
+
+// grpcStatus returns a status error carrying the same stable code as the
+// HTTP API's error responses.
+func grpcStatus(code codes.Code, reason string, message string) error {
+	return grpcStatusInfo(code, &errdetails.ErrorInfo{Reason: reason}, message)
+}
+
+func grpcStatusInfo(code codes.Code, info *errdetails.ErrorInfo, message string) error {
+	info.Domain = errorDomain
+	st, err := status.New(code, message).WithDetails(info)
+	if err != nil {
+		return status.Error(code, message)
+	}
+	return st.Err()
+}
+
+// grpcServiceError maps errors returned by the services to a status, like
+// writeServiceError does for HTTP.
+func grpcServiceError(ctx context.Context, source string, err error) error {
+	var lowQuality *ai.ErrLowQuality
+	switch {
+	case errors.As(err, &lowQuality):
+		return grpcStatusInfo(codes.InvalidArgument, &errdetails.ErrorInfo{
+			Reason: "low_quality",
+			Metadata: map[string]string{
+				"check":     lowQuality.Reason,
+				"value":     strconv.FormatFloat(lowQuality.Value, 'g', -1, 64),
+				"threshold": strconv.FormatFloat(lowQuality.Threshold, 'g', -1, 64),
+			},
+		}, lowQualityMessage(lowQuality.Reason))
+	case errors.Is(err, ai.ErrNoFace):
+		return grpcStatus(codes.InvalidArgument, "no_face", "No face was found in the image.")
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		return grpcStatus(codes.InvalidArgument, "unsupported_media_type", "The file is not a supported image. Try a JPEG or PNG file.")
+	case errors.Is(err, imaging.ErrTooLarge):
+		return grpcStatus(codes.InvalidArgument, "image_too_large", fmt.Sprintf("The image is too large. Images may be at most %d pixels wide or high, and %d megapixels.", imaging.MaxDimension, imaging.MaxPixels/1_000_000))
+	case errors.Is(err, ai.ErrBadImage):
+		return grpcStatus(codes.InvalidArgument, "bad_image", "The image could not be read. Try a JPEG or PNG file.")
+	case errors.Is(err, service.ErrAlreadyProcessed):
+		return grpcStatus(codes.AlreadyExists, "duplicate", "The image was enrolled before.")
+	case errors.Is(err, pgx.ErrNoRows):
+		return grpcStatus(codes.NotFound, "not_found", "Not found.")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, ai.ErrModelMismatch):
+		grpcLogger.ErrorContext(ctx, "Call failed", "source", source, "error", err)
+		return grpcStatus(codes.Unavailable, "model_mismatch", "Face recognition is misconfigured. Please try again later.")
+	case errors.Is(err, ai.ErrSidecarUnavailable):
+		grpcLogger.ErrorContext(ctx, "Call failed", "source", source, "error", err)
+		return grpcStatus(codes.Unavailable, "sidecar_unavailable", "Face recognition is temporarily unavailable. Please try again later.")
+	default:
+		grpcLogger.ErrorContext(ctx, "Call failed", "source", source, "error", err)
+		return grpcStatus(codes.Internal, "internal_error", "Internal error.")
+	}
+}
+
+// grpcUnaryInterceptor tags the call with a request id and logs it, like the
+// HTTP middleware.
+func grpcUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx = withGrpcRequestID(ctx)
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func grpcStreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx := withGrpcRequestID(stream.Context())
+	err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	logCall(ctx, info.FullMethod, start, err)
+	return err
+}
+
+// withGrpcRequestID takes the caller's x-request-id, or a new one when it's
+// missing or unusable, and sends it back in the response header.
+func withGrpcRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(requestIDHeader); len(ids) > 0 {
+			id = ids[0]
+		}
+	}
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, id))
+	return logging.WithRequestID(ctx, id)
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unavailable, codes.Unknown, codes.DataLoss:
+		level = slog.LevelWarn
+	}
+	grpcLogger.Log(ctx, level, "Call",
+		"method", method,
+		"code", code.String(),
+		"duration", time.Since(start))
+}
+
+// contextStream replaces the context of a server stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func toProtoMatches(results []service.SearchResult) []*facematchpb.Match {
+	matches := make([]*facematchpb.Match, 0, len(results))
+	for _, result := range results {
+		matches = append(matches, &facematchpb.Match{
+			ImageId:           result.ID,
+			PersonId:          result.PersonID,
+			CategoryId:        result.CategoryID,
+			DisplayName:       result.DisplayName,
+			DisambiguationTag: result.DisambiguationTag,
+			Similarity:        result.SimilarityScore,
+		})
+	}
+	return matches
+}
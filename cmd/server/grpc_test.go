@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/face-match/internal/ai/fake"
+	"github.com/face-match/internal/service"
+	"github.com/face-match/pkg/facematchpb"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testDatabaseEnv names a migrated database for the tests that need one.
+const testDatabaseEnv = "TEST_DATABASE_URL"
+
+// dialGrpc serves srv's gRPC service in memory and connects to it.
+func dialGrpc(t *testing.T, srv *Server) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := newGrpcServer(srv)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// assertStatus checks the code of a failed call and the reason of its
+// ErrorInfo.
+func assertStatus(t *testing.T, err error, code codes.Code, reason string) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	if !ok || err == nil {
+		t.Fatalf("got %v, want a %s status", err, code)
+	}
+	if st.Code() != code {
+		t.Errorf("code %s, want %s: %s", st.Code(), code, st.Message())
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			if info.GetReason() != reason || info.GetDomain() != errorDomain {
+				t.Errorf("ErrorInfo %s/%s, want %s/%s", info.GetDomain(), info.GetReason(), errorDomain, reason)
+			}
+			return
+		}
+	}
+	t.Errorf("no ErrorInfo in %v", st.Details())
+}
+
+func TestGrpcSearch(t *testing.T) {
+	ctx := context.Background()
+
+	client := facematchpb.NewFaceMatchClient(dialGrpc(t, newTestServer(t, newStubSidecar(t, fake.ModeNoFace))))
+	_, err := client.Search(ctx, &facematchpb.SearchRequest{
+		Query:       &facematchpb.SearchRequest_Image{Image: faceJpeg(t)},
+		CategoryIds: []int64{1},
+	})
+	assertStatus(t, err, codes.InvalidArgument, "no_face")
+
+	_, err = client.Search(ctx, &facematchpb.SearchRequest{})
+	assertStatus(t, err, codes.InvalidArgument, "missing_image")
+	_, err = client.Search(ctx, &facematchpb.SearchRequest{
+		Query:       &facematchpb.SearchRequest_ImageId{ImageId: 1},
+		CategoryIds: []int64{-1},
+	})
+	assertStatus(t, err, codes.InvalidArgument, "invalid_argument")
+
+	// A face is found, and the search fails at the database
+	client = facematchpb.NewFaceMatchClient(dialGrpc(t, newTestServer(t, newStubSidecar(t, fake.ModeFace))))
+	_, err = client.Search(ctx, &facematchpb.SearchRequest{
+		Query:       &facematchpb.SearchRequest_Image{Image: faceJpeg(t)},
+		CategoryIds: []int64{1},
+	})
+	assertStatus(t, err, codes.Internal, "internal_error")
+}
+
+// searchBatch streams the requests and returns the response, or the status
+// the server ended the stream with.
+func searchBatch(t *testing.T, client facematchpb.FaceMatchClient, requests []*facematchpb.SearchBatchRequest) (*facematchpb.SearchBatchResponse, error) {
+	t.Helper()
+	stream, err := client.SearchBatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range requests {
+		// A refused stream is reported by CloseAndRecv
+		if err := stream.Send(req); err != nil {
+			break
+		}
+	}
+	return stream.CloseAndRecv()
+}
+
+func TestGrpcSearchBatch(t *testing.T) {
+	srv := newTestServer(t, newStubSidecar(t, fake.ModeNoFace))
+	srv.config.MaxUploadSize = 64 << 10
+	client := facematchpb.NewFaceMatchClient(dialGrpc(t, srv))
+
+	image := faceJpeg(t)
+	response, err := searchBatch(t, client, []*facematchpb.SearchBatchRequest{
+		{Name: "a.jpg", Image: image, CategoryIds: []int64{1}},
+		{Name: "a.jpg", Image: image},
+		{Image: image},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.jpg", "a (2).jpg", "2"} {
+		result, ok := response.GetResults()[name]
+		if !ok {
+			t.Errorf("no result for %q in %v", name, response.GetResults())
+			continue
+		}
+		if result.GetError() != "no_face" {
+			t.Errorf("%q failed with %q, want no_face", name, result.GetError())
+		}
+	}
+
+	_, err = searchBatch(t, client, nil)
+	assertStatus(t, err, codes.InvalidArgument, "no_images")
+
+	_, err = searchBatch(t, client, []*facematchpb.SearchBatchRequest{{Image: image, CategoryIds: []int64{0}}})
+	assertStatus(t, err, codes.InvalidArgument, "invalid_argument")
+
+	// Each message fits, but together they're larger than MaxUploadSize
+	large := make([]byte, 40<<10)
+	_, err = searchBatch(t, client, []*facematchpb.SearchBatchRequest{{Image: large}, {Image: large}})
+	assertStatus(t, err, codes.ResourceExhausted, "too_large")
+
+	// The images alone fit, their names don't
+	name := strings.Repeat("n", 20<<10)
+	small := make([]byte, 10<<10)
+	_, err = searchBatch(t, client, []*facematchpb.SearchBatchRequest{{Name: name, Image: small}, {Name: name, Image: small}, {Name: name, Image: small}})
+	assertStatus(t, err, codes.ResourceExhausted, "too_large")
+}
+
+func TestGrpcSearchBatchCount(t *testing.T) {
+	client := facematchpb.NewFaceMatchClient(dialGrpc(t, newTestServer(t, newStubSidecar(t, fake.ModeNoFace))))
+
+	requests := make([]*facematchpb.SearchBatchRequest, service.MaxBatchImages+1)
+	for i := range requests {
+		requests[i] = &facematchpb.SearchBatchRequest{Image: []byte{0}}
+	}
+	_, err := searchBatch(t, client, requests)
+	assertStatus(t, err, codes.ResourceExhausted, "too_large")
+}
+
+func TestGrpcEnroll(t *testing.T) {
+	stub := newStubSidecar(t, fake.ModeFace)
+	incomplete := &facematchpb.EnrollRequest{Category: "Test", Image: faceJpeg(t)}
+
+	client := facematchpb.NewFaceMatchClient(dialGrpc(t, newTestServer(t, stub)))
+	_, err := client.Enroll(context.Background(), incomplete)
+	assertStatus(t, err, codes.PermissionDenied, "admin_disabled")
+
+	srv := newTestServer(t, stub)
+	srv.config.AdminToken = "secret"
+	client = facematchpb.NewFaceMatchClient(dialGrpc(t, srv))
+	_, err = client.Enroll(context.Background(), incomplete)
+	assertStatus(t, err, codes.Unauthenticated, "unauthorized")
+
+	wrong := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	_, err = client.Enroll(wrong, incomplete)
+	assertStatus(t, err, codes.Unauthenticated, "unauthorized")
+
+	// The right token gets as far as checking the request
+	right := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	_, err = client.Enroll(right, incomplete)
+	assertStatus(t, err, codes.InvalidArgument, "invalid_argument")
+}
+
+func TestGrpcListCategories(t *testing.T) {
+	srv := newTestServer(t, newStubSidecar(t, fake.ModeFace))
+	client := facematchpb.NewFaceMatchClient(dialGrpc(t, srv))
+	_, err := client.ListCategories(context.Background(), &facematchpb.ListCategoriesRequest{})
+	assertStatus(t, err, codes.Internal, "internal_error")
+
+	url := os.Getenv(testDatabaseEnv)
+	if url == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	srv = newTestServer(t, newStubSidecar(t, fake.ModeFace))
+	srv.pool = pool
+	client = facematchpb.NewFaceMatchClient(dialGrpc(t, srv))
+
+	response, err := client.ListCategories(context.Background(), &facematchpb.ListCategoriesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, category := range response.GetCategories() {
+		if category.GetId() <= 0 || category.GetName() == "" {
+			t.Errorf("category %v has no id or name", category)
+		}
+	}
+}
+
+func TestGrpcReflection(t *testing.T) {
+	conn := dialGrpc(t, newTestServer(t, newStubSidecar(t, fake.ModeFace)))
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: "facematch.v1.FaceMatch"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.GetFileDescriptorResponse().GetFileDescriptorProto()) == 0 {
+		t.Errorf("reflection doesn't know facematch.v1.FaceMatch: %v", response.GetErrorResponse())
+	}
+	_ = stream.CloseSend()
+}
@@ -2,143 +2,567 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"math"
+	"mime"
+	"net"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
 	"time"
 
+	"github.com/face-match/internal/ai"
 	"github.com/face-match/internal/app"
+	"github.com/face-match/internal/logging"
+	"github.com/face-match/internal/metrics"
 	"github.com/face-match/internal/service"
 	"github.com/face-match/internal/store"
+	"github.com/face-match/internal/tracing"
+	"github.com/face-match/internal/video"
+	"github.com/face-match/web"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	// How often a frame is sampled from a video when not given.
+	defaultVideoInterval = time.Second
+
+	// How much of a multipart form is kept in memory; larger files are
+	// buffered on disk. The whole request is limited by MaxUploadSize.
+	multipartMemory = 8 << 20
+
+	// How often the sidecar's model is checked again when it couldn't be
+	// verified at startup.
+	modelVerifyInterval = 30 * time.Second
+)
+
+var (
+	logger = logging.For("server")
+	tracer = otel.Tracer("github.com/face-match/cmd/server")
 )
 
 type Server struct {
-	config *app.Config
+	config   *app.Config
+	pool     *pgxpool.Pool
+	aiClient *ai.Client
+	health   *service.HealthService
 }
 
 func main() {
-	config := &app.Config{
-		AIEndpoint:  os.Getenv("AI_ENDPOINT"),
-		DatabaseUrl: os.Getenv("DATABASE_URL"),
-		DataRoot:    os.Getenv("DATA_ROOT"),
-		WebEndpoint: os.Getenv("WEB_ENDPOINT"),
+	flags := pflag.NewFlagSet("server", pflag.ExitOnError)
+	app.RegisterFlags(flags)
+	_ = flags.Parse(os.Args[1:])
+
+	config, err := app.LoadConfig(flags, "ai_endpoint", "database_url")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if app.PrintRequested(flags) {
+		if err := app.PrintConfig(os.Stdout, config); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := logging.Setup(os.Stderr, config.LogFormat, config.LogLevel); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	shutdownTracing, err := tracing.Setup(ctx, "face-match-server", config.OtlpEndpoint, config.TraceSampleRatio)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() { _ = shutdownTracing(context.Background()) }()
+
+	pool, err := store.Open(ctx, config.DatabaseUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pool.Close()
+	metrics.RegisterPool(pool)
+
+	aiClient, err := ai.NewClient(config.AIEndpoint)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var cache *ai.MemoryCache
+	if config.QueryRetention != app.RetentionNone {
+		cache = ai.NewMemoryCache(config.EmbeddingCacheSize, config.QueryRetentionTTL)
+		aiClient.UseCache(cache)
+	}
+	aiClient.RequireVerification()
+	aiClient.Start(ctx)
+
+	// Without a verified model, searches answer model_mismatch and /readyz
+	// fails until it checks out, rather than the server not starting
+	models := service.NewModelService(pool, aiClient)
+	if _, err := models.Verify(ctx); err != nil {
+		logger.Error("Sidecar model not verified, searches are refused until it is", "error", err)
+		go models.VerifyUntilDone(ctx, modelVerifyInterval)
 	}
-	config.ThumbsPath = filepath.Join(config.DataRoot, "/images/thumbs")
 
 	srv := &Server{
-		config: config,
+		config:   config,
+		pool:     pool,
+		aiClient: aiClient,
+		health:   service.NewHealthService(pool, aiClient),
 	}
 
-	mux := http.NewServeMux()
+	go service.NewJobService(config, pool, aiClient).Run(ctx, config.JobWorkers)
+	go service.NewCanaryService(pool, aiClient).Run(ctx, config.CanaryInterval, service.DefaultCanaryThreshold)
+	go service.NewRetentionService(config, pool, cache).Run(ctx)
 
-	mux.Handle("/", http.FileServer(http.Dir("web/static")))
-	mux.HandleFunc("/api/categories", srv.handleCategories)
-	mux.HandleFunc("/api/search", srv.handleSearch)
-	mux.Handle("/thumbs/", http.StripPrefix("/thumbs/", http.FileServer(http.Dir("data/images/thumbs"))))
+	staticFiles := web.Static()
+	if config.WebDir != "" {
+		staticFiles = os.DirFS(config.WebDir)
+	}
+	assets, err := newAssetHandler(staticFiles, config.WebDir != "")
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	server := &http.Server{
 		Addr:         config.WebEndpoint,
-		Handler:      loggingMiddleware(mux),
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Handler:      srv.handler(assets),
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+		IdleTimeout:  config.IdleTimeout,
 	}
 
-	log.Printf("Listening at %s", config.WebEndpoint)
+	if config.GrpcEndpoint != "" {
+		listener, err := net.Listen("tcp", config.GrpcEndpoint)
+		if err != nil {
+			log.Fatal(err)
+		}
+		grpcServer := newGrpcServer(srv)
+		logger.Info("Listening for gRPC", "address", config.GrpcEndpoint)
+		go func() { log.Fatal(grpcServer.Serve(listener)) }()
+	}
+
+	logger.Info("Listening", "address", config.WebEndpoint)
 	log.Fatal(server.ListenAndServe())
 }
 
+// handler routes every HTTP endpoint, assets serving the web UI, and wraps
+// them in the middleware.
+func (srv *Server) handler(assets http.Handler) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("/", assets)
+	mux.HandleFunc("/healthz", srv.handleHealthz)
+	mux.HandleFunc("/readyz", srv.handleReadyz)
+	mux.HandleFunc("/api/categories", srv.handleCategories)
+	mux.HandleFunc("/api/info", srv.handleInfo)
+	mux.HandleFunc("/api/search", srv.handleSearch)
+	mux.HandleFunc("/api/search/batch", srv.handleSearchBatch)
+	mux.HandleFunc("/api/search/video", srv.handleSearchVideo)
+	mux.HandleFunc("/api/jobs", srv.handleJobs)
+	mux.HandleFunc("/api/jobs/{id}", srv.handleJob)
+	mux.HandleFunc("/api/v1/openapi.yaml", srv.handleOpenAPI)
+	mux.HandleFunc("/api/v1/categories", srv.handleV1Categories)
+	mux.HandleFunc("/api/v1/info", srv.handleV1Info)
+	mux.HandleFunc("/api/v1/search", srv.handleV1Search)
+	mux.HandleFunc("/api/v1/search/batch", srv.handleV1SearchBatch)
+	mux.HandleFunc("/api/v1/search/video", srv.handleV1SearchVideo)
+	mux.HandleFunc("/api/v1/jobs", srv.handleV1Jobs)
+	mux.HandleFunc("/api/v1/jobs/{id}", srv.handleV1Job)
+	mux.HandleFunc("/api/v1/admin/people", srv.requireAdmin(srv.handleAdminPeople))
+	mux.HandleFunc("/api/v1/admin/people/{id}", srv.requireAdmin(srv.handleAdminPerson))
+	mux.HandleFunc("/api/v1/admin/canaries/check", srv.requireAdmin(srv.handleAdminCanaries))
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/thumbs/", http.StripPrefix("/thumbs/", http.FileServer(http.Dir(srv.config.ThumbsPath))))
+
+	// Innermost first. routeMiddleware reads the pattern the mux sets on the
+	// request, so nothing between them may replace the request.
+	var handler http.Handler = mux
+	handler = limitBodyMiddleware(srv.config.MaxUploadSize, handler)
+	handler = corsMiddleware(srv.config.CorsOrigins, handler)
+	handler = securityHeadersMiddleware(handler)
+	handler = routeMiddleware(handler)
+	handler = loggingMiddleware(handler)
+	handler = requestIDMiddleware(handler)
+	handler = otelhttp.NewHandler(handler, "http")
+	return handler
+}
+
 func (srv *Server) handleCategories(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
 		return
 	}
 
-	pool, err := store.Open(r.Context(), srv.config.DatabaseUrl)
+	categoryStore := store.NewCategoryStore(srv.pool)
+
+	categories, err := categoryStore.List(r.Context())
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		writeServiceError(w, r, "category store", err)
 		return
 	}
-	categoryStore := store.NewCategoryStore(pool)
 
-	categories, err := categoryStore.List(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(categories); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", http.StatusText(http.StatusInternalServerError))
+		return
+	}
+}
+
+// handleHealthz reports that the process is up, without checking anything
+// it depends on.
+func (srv *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"status":"ok"}` + "\n"))
+}
+
+// handleReadyz reports whether every dependency needed to serve searches is
+// available, with a status per dependency.
+func (srv *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	readiness := srv.health.Readiness(r.Context())
+
+	status := http.StatusOK
+	if !readiness.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(readiness)
+}
+
+// handleInfo describes the sidecar's model and the database's active model.
+func (srv *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	status, err := service.NewModelService(srv.pool, srv.aiClient).Status(r.Context())
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		writeServiceError(w, r, "model service", err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(categories); err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", http.StatusText(http.StatusInternalServerError))
 		return
 	}
 }
 
+// handleSearch accepts either an uploaded "image", or the "image_id" or
+// "person_id" of something already stored. The latter two reuse the stored
+// embeddings and exclude the source person from the results.
 func (srv *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
 		return
 	}
 
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
-		http.Error(w, "invalid form", http.StatusBadRequest)
+	if err := srv.parseMultipart(r); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		writeFormError(w, err)
 		return
 	}
 
 	// Load form data
 
-	categories := r.Form["categories[]"]
-	categoryIDs := make([]int64, 0, len(categories))
-	for _, category := range categories {
-		id, err := strconv.ParseInt(category, 10, 64)
-		if err == nil {
-			categoryIDs = append(categoryIDs, id)
+	categoryIDs := parseCategoryIDs(r)
+
+	imageID, err := parseOptionalID(r.FormValue("image_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_form", "The image_id is not valid.")
+		return
+	}
+	personID, err := parseOptionalID(r.FormValue("person_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_form", "The person_id is not valid.")
+		return
+	}
+
+	// Perform the search:
+
+	searchService := service.NewSearchService(srv.config, srv.pool, srv.aiClient)
+
+	var results []service.SearchResult
+	switch {
+	case imageID != 0:
+		results, err = searchService.SearchByImage(r.Context(), categoryIDs, imageID)
+	case personID != 0:
+		results, err = searchService.SearchByPerson(r.Context(), categoryIDs, personID)
+	default:
+		imageBytes, formErr := readFormFile(r, "image")
+		if formErr != nil {
+			writeFormError(w, formErr)
+			return
 		}
+		results, err = searchService.Search(r.Context(), categoryIDs, imageBytes)
+	}
+	if err != nil {
+		writeServiceError(w, r, "search service", err)
+		return
 	}
 
-	file, _, err := r.FormFile("image")
-	if err != nil || file == nil {
-		http.Error(w, "invalid form", http.StatusBadRequest)
+	// Return the result:
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", http.StatusText(http.StatusInternalServerError))
+		return
 	}
-	defer func() { _ = file.Close() }()
+}
 
-	buf := bytes.NewBuffer(nil)
-	if _, err := io.Copy(buf, file); err != nil {
-		http.Error(w, "invalid form", http.StatusBadRequest)
+// handleSearchBatch searches every uploaded image. Images are sent either as
+// repeated "images" files, as a zip file in "archive", or as a zip request
+// body. The response maps each filename to its results.
+func (srv *Server) handleSearchBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
 	}
 
-	// Perform the search:
+	images, err := srv.readBatchRequest(r)
+	if err != nil {
+		writeFormError(w, err)
+		return
+	}
+	categoryIDs := parseCategoryIDs(r)
+	if len(images) == 0 {
+		writeError(w, http.StatusBadRequest, "no_images", "No images were uploaded.")
+		return
+	}
 
-	pool, err := store.Open(r.Context(), srv.config.DatabaseUrl)
+	searchService := service.NewSearchService(srv.config, srv.pool, srv.aiClient)
+
+	results := searchService.SearchBatch(r.Context(), categoryIDs, images, srv.config.BatchConcurrency)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", http.StatusText(http.StatusInternalServerError))
+		return
+	}
+}
+
+// handleSearchVideo samples frames from the uploaded "video" every "interval"
+// seconds and returns which people appear when.
+func (srv *Server) handleSearchVideo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	if err := srv.parseMultipart(r); err != nil {
+		writeFormError(w, err)
+		return
+	}
+
+	categoryIDs := parseCategoryIDs(r)
+
+	interval := defaultVideoInterval
+	if value := r.FormValue("interval"); value != "" {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid_form", "The interval is not valid.")
+			return
+		}
+		interval = time.Duration(seconds * float64(time.Second))
+	}
+
+	videoBytes, err := readFormFile(r, "video")
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		writeFormError(w, err)
 		return
 	}
-	searchService := service.NewSearchService(srv.config, pool)
 
-	results, err := searchService.Search(r.Context(), categoryIDs, buf.Bytes())
+	searchService := service.NewSearchService(srv.config, srv.pool, srv.aiClient)
+	extractor := video.NewExtractor(srv.config.FrameExtractor)
+
+	timeline, err := searchService.SearchVideo(r.Context(), categoryIDs, extractor, videoBytes, interval, srv.config.BatchConcurrency)
 	if err != nil {
-		log.Printf("search service error: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		writeServiceError(w, r, "search service", err)
 		return
 	}
 
-	// Return the result:
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(results); err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	if err := json.NewEncoder(w).Encode(timeline); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", http.StatusText(http.StatusInternalServerError))
+		return
+	}
+}
+
+// handleJobs queues a search job. It accepts the same input as the batch
+// search, plus an optional "webhook_url" that receives the finished job.
+func (srv *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
 		return
 	}
+
+	if srv.config.PrivacyMode {
+		writeError(w, http.StatusForbidden, "jobs_disabled", "Search jobs are disabled, as they would store the images.")
+		return
+	}
+
+	images, err := srv.readBatchRequest(r)
+	if err != nil {
+		writeFormError(w, err)
+		return
+	}
+	categoryIDs := parseCategoryIDs(r)
+	if len(images) == 0 {
+		writeError(w, http.StatusBadRequest, "no_images", "No images were uploaded.")
+		return
+	}
+
+	jobService := service.NewJobService(srv.config, srv.pool, srv.aiClient)
+	id, err := jobService.Submit(r.Context(), categoryIDs, images, r.FormValue("webhook_url"))
+	if err != nil {
+		writeServiceError(w, r, "job service", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/jobs/"+id)
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(service.Job{ID: id, Status: store.JobQueued})
 }
 
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
-	})
+func (srv *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	jobService := service.NewJobService(srv.config, srv.pool, srv.aiClient)
+	job, err := jobService.Fetch(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, "job service", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", http.StatusText(http.StatusInternalServerError))
+		return
+	}
+}
+
+// readBatchRequest reads the images of a batch, which are sent as repeated
+// "images" files, as a zip file in "archive", or as a zip request body. The
+// rest of the form is parsed too. Batches hold at most
+// service.MaxBatchImages images, however they're sent.
+func (srv *Server) readBatchRequest(r *http.Request) ([]service.BatchImage, error) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/zip" {
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		return service.ReadZipImages(body, srv.config.MaxUploadSize)
+	}
+
+	if err := srv.parseMultipart(r); err != nil {
+		return nil, err
+	}
+
+	headers := append(r.MultipartForm.File["images"], r.MultipartForm.File["images[]"]...)
+	if len(headers) > service.MaxBatchImages {
+		return nil, service.ErrTooManyImages
+	}
+	var images []service.BatchImage
+	for _, header := range headers {
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		imageBytes, err := io.ReadAll(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, service.BatchImage{Name: header.Filename, Bytes: imageBytes})
+	}
+
+	if len(r.MultipartForm.File["archive"]) > 0 {
+		archive, err := readFormFile(r, "archive")
+		if err != nil {
+			return nil, err
+		}
+		unpacked, err := service.ReadZipImages(archive, srv.config.MaxUploadSize)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, unpacked...)
+		if len(images) > service.MaxBatchImages {
+			return nil, service.ErrTooManyImages
+		}
+	}
+
+	return images, nil
+}
+
+func parseCategoryIDs(r *http.Request) []int64 {
+	categories := r.Form["categories[]"]
+	categoryIDs := make([]int64, 0, len(categories))
+	for _, category := range categories {
+		id, err := strconv.ParseInt(category, 10, 64)
+		if err == nil {
+			categoryIDs = append(categoryIDs, id)
+		}
+	}
+	return categoryIDs
+}
+
+// parseMultipart reads the multipart form in its own span, as large uploads
+// can take a while. In privacy mode, files are never buffered on disk.
+func (srv *Server) parseMultipart(r *http.Request) error {
+	memory := int64(multipartMemory)
+	if srv.config.PrivacyMode {
+		// Files are only written to disk past this, which is never reached;
+		// limitBodyMiddleware bounds the body instead. One less than the
+		// maximum, which the multipart package would count as overflowing.
+		memory = math.MaxInt64 - 1
+	}
+
+	_, span := tracer.Start(r.Context(), "parse multipart")
+	err := r.ParseMultipartForm(memory)
+	if errors.Is(err, http.ErrNotMultipart) {
+		span.End()
+		return err
+	}
+	tracing.End(span, err)
+	return err
+}
+
+func readFormFile(r *http.Request, key string) ([]byte, error) {
+	file, _, err := r.FormFile(key)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	buf := bytes.NewBuffer(nil)
+	if _, err := io.Copy(buf, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseOptionalID parses a positive id, treating an empty value as 0.
+func parseOptionalID(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid id: %q", value)
+	}
+	return id, nil
 }
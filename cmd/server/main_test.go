@@ -0,0 +1,101 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/face-match/internal/ai/fake"
+	"github.com/face-match/internal/app"
+	"github.com/face-match/internal/service"
+)
+
+func TestParseMultipartPrivacyMode(t *testing.T) {
+	for _, privacy := range []bool{false, true} {
+		dir := t.TempDir()
+		t.Setenv("TMPDIR", dir)
+
+		body := bytes.NewBuffer(nil)
+		form := multipart.NewWriter(body)
+		part, err := form.CreateFormFile("image", "large.jpg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Larger than multipartMemory, so it would normally go to disk
+		if _, err := part.Write(make([]byte, multipartMemory+1<<20)); err != nil {
+			t.Fatal(err)
+		}
+		if err := form.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		config := app.Default()
+		config.PrivacyMode = privacy
+		config.MaxUploadSize = 32 << 20
+		srv := &Server{config: config}
+
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/search", body)
+		r.Header.Set("Content-Type", form.FormDataContentType())
+		if err := srv.parseMultipart(r); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if privacy && len(entries) > 0 {
+			t.Errorf("privacy mode buffered %d files on disk", len(entries))
+		}
+		if !privacy && len(entries) == 0 {
+			t.Error("large file wasn't buffered on disk outside privacy mode")
+		}
+		_ = r.MultipartForm.RemoveAll()
+	}
+}
+
+func TestBatchImageCap(t *testing.T) {
+	srv := newTestServer(t, newStubSidecar(t, fake.ModeFace))
+
+	// The uploaded files and the archive fit on their own, but not together
+	var archive bytes.Buffer
+	zipWriter := zip.NewWriter(&archive)
+	for i := 0; i < service.MaxBatchImages; i++ {
+		if _, err := zipWriter.Create(fmt.Sprintf("%d.jpg", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, name := range []string{"a.jpg", "archive.zip"} {
+		field := "images"
+		content := faceJpeg(t)
+		if name == "archive.zip" {
+			field, content = "archive", archive.Bytes()
+		}
+		part, err := form.CreateFormFile(field, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = part.Write(content)
+	}
+	_ = form.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/search/batch", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	response := httptest.NewRecorder()
+	srv.handler(http.NotFoundHandler()).ServeHTTP(response, request)
+
+	if response.Code != http.StatusRequestEntityTooLarge || !strings.Contains(response.Body.String(), "too_large") {
+		t.Errorf("status %d, want 413 too_large: %s", response.Code, response.Body)
+	}
+}
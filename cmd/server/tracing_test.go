@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/ai/fake"
+	"github.com/face-match/internal/app"
+	"github.com/face-match/internal/tracing"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// unreachableDatabase is a pool whose connections are all refused. Nothing
+// connects until a query runs, so the spans around it are still recorded.
+const unreachableDatabase = "postgres://test@127.0.0.1:1/test?connect_timeout=1"
+
+// stubSidecar is the fake sidecar, remembering the traceparent headers of
+// embedding requests.
+type stubSidecar struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	traceparents []string
+}
+
+// newStubSidecar starts a fake sidecar answering in the given fake mode.
+func newStubSidecar(t *testing.T, mode string) *stubSidecar {
+	t.Helper()
+	stub := &stubSidecar{}
+	handler := fake.New(mode, ai.ModelInfo{Name: "fake", Version: "1", Dim: 512, Normalization: "l2"})
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/embed-largest-face" {
+			stub.mu.Lock()
+			stub.traceparents = append(stub.traceparents, r.Header.Get("traceparent"))
+			stub.mu.Unlock()
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+// newTestServer is a Server whose sidecar is stub and whose database can't
+// be reached. The quality checks are off.
+func newTestServer(t *testing.T, stub *stubSidecar) *Server {
+	t.Helper()
+	pool, err := pgxpool.New(context.Background(), unreachableDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	aiClient, err := ai.NewClient(stub.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := app.Default()
+	config.Quality = ai.QualityPolicy{}
+	return &Server{config: config, pool: pool, aiClient: aiClient}
+}
+
+// recordSpans installs a tracer provider recording every span in memory for
+// the rest of the test.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), "test", 1)
+
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return exporter
+}
+
+// faceJpeg is an image the fake sidecar finds a face in.
+func faceJpeg(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x ^ y), G: uint8(x), B: uint8(y), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSearchTrace(t *testing.T) {
+	exporter := recordSpans(t)
+	stub := newStubSidecar(t, fake.ModeFace)
+	srv := newTestServer(t, stub)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "face.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write(faceJpeg(t))
+	_ = form.WriteField("category_id", "1")
+	_ = form.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/search", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	response := httptest.NewRecorder()
+	srv.handler(http.NotFoundHandler()).ServeHTTP(response, request)
+
+	// The search itself fails at the database, which is traced too
+	if response.Code < 500 {
+		t.Fatalf("status %d, want the unreachable database to fail the search: %s", response.Code, response.Body)
+	}
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	find := func(name string) tracetest.SpanStub {
+		t.Helper()
+		span, ok := spans[name]
+		if !ok {
+			names := make([]string, 0, len(spans))
+			for n := range spans {
+				names = append(names, n)
+			}
+			t.Fatalf("no %q span among %v", name, names)
+		}
+		return span
+	}
+	childOf := func(child, parent tracetest.SpanStub) {
+		t.Helper()
+		if child.Parent.SpanID() != parent.SpanContext.SpanID() {
+			t.Errorf("%q's parent is %s, want %q (%s)", child.Name, child.Parent.SpanID(), parent.Name, parent.SpanContext.SpanID())
+		}
+	}
+
+	root := find("POST /api/v1/search")
+	if root.Parent.IsValid() {
+		t.Errorf("the HTTP span has a parent, %s", root.Parent.SpanID())
+	}
+	search := find("SearchService.Search")
+	childOf(search, root)
+	fetchFace := find("ai.FetchFace")
+	childOf(fetchFace, search)
+	imageSearch := find("ImageStore.Search")
+	childOf(imageSearch, search)
+	if len(imageSearch.Events) == 0 {
+		t.Errorf("ImageStore.Search recorded no error")
+	}
+
+	for _, span := range []tracetest.SpanStub{search, fetchFace, imageSearch} {
+		if span.SpanContext.TraceID() != root.SpanContext.TraceID() {
+			t.Errorf("%q is in trace %s, want %s", span.Name, span.SpanContext.TraceID(), root.SpanContext.TraceID())
+		}
+	}
+
+	// The sidecar request is a child of ai.FetchFace, and carries its own
+	// span's context
+	stub.mu.Lock()
+	traceparents := stub.traceparents
+	stub.mu.Unlock()
+	if len(traceparents) != 1 {
+		t.Fatalf("the sidecar got %d embedding requests, want 1", len(traceparents))
+	}
+	parts := strings.Split(traceparents[0], "-")
+	if len(parts) != 4 {
+		t.Fatalf("traceparent %q is malformed", traceparents[0])
+	}
+	if parts[1] != root.SpanContext.TraceID().String() {
+		t.Errorf("traceparent %q is not in trace %s", traceparents[0], root.SpanContext.TraceID())
+	}
+	var clientSpan *tracetest.SpanStub
+	for _, span := range exporter.GetSpans() {
+		if span.SpanKind == trace.SpanKindClient && span.SpanContext.SpanID().String() == parts[2] {
+			clientSpan = &span
+		}
+	}
+	if clientSpan == nil {
+		t.Fatalf("traceparent %q names no client span", traceparents[0])
+	}
+	childOf(*clientSpan, fetchFace)
+}
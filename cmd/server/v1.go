@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/service"
+	"github.com/face-match/internal/store"
+	"github.com/face-match/internal/video"
+	"github.com/face-match/pkg/api"
+)
+
+// The /api/v1 handlers answer with the documents of package api, which are
+// described by pkg/api/openapi.yaml. Their input is checked strictly: unlike
+// the unversioned API, an invalid category_id is an error rather than
+// ignored.
+
+func (srv *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(api.OpenAPI)
+}
+
+func (srv *Server) handleV1Categories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	categories, err := store.NewCategoryStore(srv.pool).List(r.Context())
+	if err != nil {
+		writeServiceError(w, r, "category store", err)
+		return
+	}
+
+	out := api.CategoryList{Categories: make([]api.Category, 0, len(categories))}
+	for _, category := range categories {
+		out.Categories = append(out.Categories, api.Category{ID: category.ID, Name: category.DisplayName})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (srv *Server) handleV1Info(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	status, err := service.NewModelService(srv.pool, srv.aiClient).Status(r.Context())
+	if err != nil {
+		writeServiceError(w, r, "model service", err)
+		return
+	}
+
+	out := api.Info{Sidecar: toModel(status.Sidecar)}
+	if status.ActiveModel != nil {
+		out.ActiveModel = &api.Model{
+			Name:          status.ActiveModel.Name,
+			Version:       status.ActiveModel.Version,
+			Dim:           status.ActiveModel.Dim,
+			Normalization: status.ActiveModel.Normalization,
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleV1Search accepts either an uploaded "image", or the "image_id" or
+// "person_id" of something already stored, like handleSearch.
+func (srv *Server) handleV1Search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	if err := srv.parseMultipart(r); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		writeFormError(w, err)
+		return
+	}
+
+	categoryIDs, ok := formCategoryIDs(w, r)
+	if !ok {
+		return
+	}
+	imageID, err := parseOptionalID(r.FormValue("image_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_form", "The image_id is not valid.")
+		return
+	}
+	personID, err := parseOptionalID(r.FormValue("person_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_form", "The person_id is not valid.")
+		return
+	}
+
+	searchService := service.NewSearchService(srv.config, srv.pool, srv.aiClient)
+
+	var results []service.SearchResult
+	switch {
+	case imageID != 0:
+		results, err = searchService.SearchByImage(r.Context(), categoryIDs, imageID)
+	case personID != 0:
+		results, err = searchService.SearchByPerson(r.Context(), categoryIDs, personID)
+	default:
+		imageBytes, formErr := readFormFile(r, "image")
+		if errors.Is(formErr, http.ErrMissingFile) {
+			writeError(w, http.StatusBadRequest, "missing_image", "Upload an image, or give an image_id or person_id.")
+			return
+		}
+		if formErr != nil {
+			writeFormError(w, formErr)
+			return
+		}
+		if categoryIDs, err = searchService.DefaultCategoryIDs(r.Context(), categoryIDs); err == nil {
+			results, err = searchService.Search(r.Context(), categoryIDs, imageBytes)
+		}
+	}
+	if err != nil {
+		writeServiceError(w, r, "search service", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.SearchResponse{Matches: toMatches(results)})
+}
+
+func (srv *Server) handleV1SearchBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	images, err := srv.readBatchRequest(r)
+	if err != nil {
+		writeFormError(w, err)
+		return
+	}
+	categoryIDs, ok := formCategoryIDs(w, r)
+	if !ok {
+		return
+	}
+	if len(images) == 0 {
+		writeError(w, http.StatusBadRequest, "no_images", "No images were uploaded.")
+		return
+	}
+
+	searchService := service.NewSearchService(srv.config, srv.pool, srv.aiClient)
+	categoryIDs, err = searchService.DefaultCategoryIDs(r.Context(), categoryIDs)
+	if err != nil {
+		writeServiceError(w, r, "search service", err)
+		return
+	}
+	results := searchService.SearchBatch(r.Context(), categoryIDs, images, srv.config.BatchConcurrency)
+
+	writeJSON(w, http.StatusOK, api.BatchResponse{Results: toBatchResults(results)})
+}
+
+func (srv *Server) handleV1SearchVideo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	if err := srv.parseMultipart(r); err != nil {
+		writeFormError(w, err)
+		return
+	}
+
+	categoryIDs, ok := formCategoryIDs(w, r)
+	if !ok {
+		return
+	}
+
+	interval := defaultVideoInterval
+	if value := r.FormValue("interval"); value != "" {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid_form", "The interval is not valid.")
+			return
+		}
+		interval = time.Duration(seconds * float64(time.Second))
+	}
+
+	videoBytes, err := readFormFile(r, "video")
+	if errors.Is(err, http.ErrMissingFile) {
+		writeError(w, http.StatusBadRequest, "missing_video", "Upload a video.")
+		return
+	}
+	if err != nil {
+		writeFormError(w, err)
+		return
+	}
+
+	searchService := service.NewSearchService(srv.config, srv.pool, srv.aiClient)
+	categoryIDs, err = searchService.DefaultCategoryIDs(r.Context(), categoryIDs)
+	if err != nil {
+		writeServiceError(w, r, "search service", err)
+		return
+	}
+	extractor := video.NewExtractor(srv.config.FrameExtractor)
+
+	timeline, err := searchService.SearchVideo(r.Context(), categoryIDs, extractor, videoBytes, interval, srv.config.BatchConcurrency)
+	if err != nil {
+		writeServiceError(w, r, "search service", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toVideoResponse(timeline))
+}
+
+func (srv *Server) handleV1Jobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	if srv.config.PrivacyMode {
+		writeError(w, http.StatusForbidden, "jobs_disabled", "Search jobs are disabled, as they would store the images.")
+		return
+	}
+
+	images, err := srv.readBatchRequest(r)
+	if err != nil {
+		writeFormError(w, err)
+		return
+	}
+	categoryIDs, ok := formCategoryIDs(w, r)
+	if !ok {
+		return
+	}
+	if len(images) == 0 {
+		writeError(w, http.StatusBadRequest, "no_images", "No images were uploaded.")
+		return
+	}
+
+	// Resolved now, as job workers search only the categories a job was
+	// submitted with
+	categoryIDs, err = service.NewSearchService(srv.config, srv.pool, srv.aiClient).DefaultCategoryIDs(r.Context(), categoryIDs)
+	if err != nil {
+		writeServiceError(w, r, "search service", err)
+		return
+	}
+
+	jobService := service.NewJobService(srv.config, srv.pool, srv.aiClient)
+	id, err := jobService.Submit(r.Context(), categoryIDs, images, r.FormValue("webhook_url"))
+	if err != nil {
+		writeServiceError(w, r, "job service", err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/jobs/"+id)
+	writeJSON(w, http.StatusAccepted, api.Job{ID: id, Status: api.JobQueued})
+}
+
+func (srv *Server) handleV1Job(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	jobService := service.NewJobService(srv.config, srv.pool, srv.aiClient)
+	job, err := jobService.Fetch(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, "job service", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toJob(job))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// formCategoryIDs parses the repeated "category_id" field, writing an error
+// response when any of them isn't valid.
+func formCategoryIDs(w http.ResponseWriter, r *http.Request) ([]int64, bool) {
+	categoryIDs, err := parseIDList(r.Form["category_id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_form", "The category_id is not valid.")
+		return nil, false
+	}
+	return categoryIDs, true
+}
+
+func parseIDList(values []string) ([]int64, error) {
+	ids := make([]int64, 0, len(values))
+	for _, value := range values {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid id: %q", value)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func toMatches(results []service.SearchResult) []api.Match {
+	matches := make([]api.Match, 0, len(results))
+	for _, result := range results {
+		matches = append(matches, api.Match{
+			ImageID:           result.ID,
+			PersonID:          result.PersonID,
+			CategoryID:        result.CategoryID,
+			DisplayName:       result.DisplayName,
+			DisambiguationTag: result.DisambiguationTag,
+			Similarity:        result.SimilarityScore,
+		})
+	}
+	return matches
+}
+
+func toBatchResults(results map[string]service.BatchResult) map[string]api.BatchResult {
+	out := make(map[string]api.BatchResult, len(results))
+	for name, result := range results {
+		out[name] = api.BatchResult{Matches: toMatches(result.Results), Error: result.Error}
+	}
+	return out
+}
+
+func toVideoResponse(timeline *service.VideoTimeline) api.VideoResponse {
+	out := api.VideoResponse{
+		Frames:      make([]api.VideoFrame, 0, len(timeline.Frames)),
+		Appearances: make([]api.Appearance, 0, len(timeline.Appearances)),
+	}
+	for _, frame := range timeline.Frames {
+		out.Frames = append(out.Frames, api.VideoFrame{
+			Index:   frame.Index,
+			Seconds: frame.Seconds,
+			Matches: toMatches(frame.Results),
+			Error:   frame.Error,
+		})
+	}
+	for _, appearance := range timeline.Appearances {
+		out.Appearances = append(out.Appearances, api.Appearance{
+			PersonID:          appearance.PersonID,
+			CategoryID:        appearance.CategoryID,
+			DisplayName:       appearance.DisplayName,
+			DisambiguationTag: appearance.DisambiguationTag,
+			StartSeconds:      appearance.StartSeconds,
+			EndSeconds:        appearance.EndSeconds,
+			Frames:            appearance.Frames,
+			BestSimilarity:    appearance.BestScore,
+		})
+	}
+	return out
+}
+
+func toJob(job *service.Job) api.Job {
+	out := api.Job{
+		ID:         job.ID,
+		Status:     job.Status,
+		CreatedAt:  &job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+		Error:      job.Error,
+	}
+	if job.Results != nil {
+		out.Results = toBatchResults(job.Results)
+	}
+	return out
+}
+
+func toModel(info *ai.ModelInfo) *api.Model {
+	if info == nil {
+		return nil
+	}
+	model := api.Model(*info)
+	return &model
+}
@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// CacheKey identifies image content; it is the SHA-256 of the uploaded bytes.
+type CacheKey [sha256.Size]byte
+
+// CacheEntry is what the sidecar returned for an image. Quality checks are
+// not cached, since the thresholds depend on who is asking.
+type CacheEntry struct {
+	Model     string
+	Embedding []float32
+	BBox      []float64
+	DetScore  float64
+}
+
+// Cache remembers embeddings so the same image isn't sent to the sidecar
+// twice. Get returns nil for a miss. Entries are per model.
+type Cache interface {
+	Get(ctx context.Context, key CacheKey, model string) (*CacheEntry, error)
+	Put(ctx context.Context, key CacheKey, entry *CacheEntry) error
+}
+
+type memoryCacheKey struct {
+	key   CacheKey
+	model string
+}
+
+type memoryCacheItem struct {
+	key     memoryCacheKey
+	entry   *CacheEntry
+	expires time.Time
+}
+
+// MemoryCache is an in-process LRU cache whose entries also expire.
+type MemoryCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	order *list.List // Most recently used first
+	items map[memoryCacheKey]*list.Element
+}
+
+// NewMemoryCache returns a cache of up to size entries, each kept for at
+// most ttl after it was stored.
+func NewMemoryCache(size int, ttl time.Duration) *MemoryCache {
+	return &MemoryCache{
+		size:  size,
+		ttl:   ttl,
+		order: list.New(),
+		items: make(map[memoryCacheKey]*list.Element, size),
+	}
+}
+
+func (c *MemoryCache) Get(_ context.Context, key CacheKey, model string) (*CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[memoryCacheKey{key: key, model: model}]
+	if !ok {
+		return nil, nil
+	}
+	item := element.Value.(*memoryCacheItem)
+	if time.Now().After(item.expires) {
+		c.order.Remove(element)
+		delete(c.items, item.key)
+		return nil, nil
+	}
+	c.order.MoveToFront(element)
+	return item.entry, nil
+}
+
+func (c *MemoryCache) Put(_ context.Context, key CacheKey, entry *CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k := memoryCacheKey{key: key, model: entry.Model}
+	expires := time.Now().Add(c.ttl)
+	if element, ok := c.items[k]; ok {
+		item := element.Value.(*memoryCacheItem)
+		item.entry, item.expires = entry, expires
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.items[k] = c.order.PushFront(&memoryCacheItem{key: k, entry: entry, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryCacheItem).key)
+	}
+	return nil
+}
+
+// RemoveExpired drops every entry past its ttl and returns how many there
+// were. Expired entries are never returned, but otherwise stay in memory
+// until they're the least recently used.
+func (c *MemoryCache) RemoveExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for element := c.order.Back(); element != nil; {
+		previous := element.Prev()
+		if item := element.Value.(*memoryCacheItem); now.After(item.expires) {
+			c.order.Remove(element)
+			delete(c.items, item.key)
+			removed++
+		}
+		element = previous
+	}
+	return removed
+}
@@ -0,0 +1,345 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/face-match/internal/logging"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const (
+	requestTimeout = 60 * time.Second
+
+	// Attempts per embedding, each on the next best endpoint.
+	maxAttempts = 3
+	retryBase   = 250 * time.Millisecond
+
+	// Consecutive failures that open an endpoint's circuit, and how long it
+	// stays open before a single trial request is let through.
+	breakerThreshold = 5
+	breakerCooldown  = 15 * time.Second
+
+	healthInterval = 10 * time.Second
+	healthTimeout  = 3 * time.Second
+)
+
+var logger = logging.For("ai")
+
+// Client talks to one or more sidecars. It is safe for concurrent use and
+// should be created once and shared, so that connections are reused.
+type Client struct {
+	http      *http.Client
+	endpoints []*endpoint
+	next      atomic.Uint64
+	cache     Cache
+
+	mu    sync.Mutex
+	info  *ModelInfo
+	model string // Identifies the embedding model in cache keys
+
+	gated    bool // Embedding waits for MarkVerified
+	verified atomic.Bool
+}
+
+type endpoint struct {
+	url        string
+	inFlight   atomic.Int64
+	healthy    atomic.Bool
+	compatible atomic.Bool // Reports the same model as the handshake
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool // A half-open trial request is in flight
+}
+
+// NewClient creates a client for a comma separated list of sidecar URLs.
+func NewClient(endpoints string) (*Client, error) {
+	client := &Client{
+		http: &http.Client{
+			Timeout: requestTimeout,
+			// Embedding requests carry the trace context to the sidecar
+			Transport: otelhttp.NewTransport(&http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				MaxIdleConnsPerHost: 16,
+				IdleConnTimeout:     90 * time.Second,
+			}, otelhttp.WithFilter(func(r *http.Request) bool {
+				return strings.HasSuffix(r.URL.Path, "/embed-largest-face")
+			})),
+		},
+	}
+
+	for _, url := range strings.Split(endpoints, ",") {
+		url = strings.TrimRight(strings.TrimSpace(url), "/")
+		if url == "" {
+			continue
+		}
+		ep := &endpoint{url: url}
+		ep.healthy.Store(true)
+		ep.compatible.Store(true)
+		client.endpoints = append(client.endpoints, ep)
+	}
+	if len(client.endpoints) == 0 {
+		return nil, fmt.Errorf("ai: AIEndpoint is required")
+	}
+	return client, nil
+}
+
+// Endpoints returns the sidecar URLs the client balances across.
+func (c *Client) Endpoints() []string {
+	urls := make([]string, len(c.endpoints))
+	for i, ep := range c.endpoints {
+		urls[i] = ep.url
+	}
+	return urls
+}
+
+// UseCache makes the client consult the cache before calling the sidecar.
+// It must be called before the client is used.
+func (c *Client) UseCache(cache Cache) {
+	c.cache = cache
+}
+
+// RequireVerification makes the client refuse to embed with
+// ErrModelMismatch until MarkVerified is called, for when the model must be
+// checked against the stored embeddings first. It must be called before the
+// client is used.
+func (c *Client) RequireVerification() {
+	c.gated = true
+}
+
+// MarkVerified lets a client that requires verification embed.
+func (c *Client) MarkVerified() {
+	c.verified.Store(true)
+}
+
+func (c *Client) checkVerified() error {
+	if c.gated && !c.verified.Load() {
+		return fmt.Errorf("%w: the sidecar's model is not verified yet", ErrModelMismatch)
+	}
+	return nil
+}
+
+// Start probes every endpoint's /healthz in the background until ctx is
+// cancelled. Unhealthy endpoints are skipped while others are available.
+func (c *Client) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(healthInterval)
+		defer ticker.Stop()
+		for {
+			c.probe(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (c *Client) probe(ctx context.Context) {
+	for _, ep := range c.endpoints {
+		err := c.checkHealth(ctx, ep)
+		if ctx.Err() != nil {
+			return
+		}
+		healthy := err == nil
+		if ep.healthy.Swap(healthy) != healthy {
+			if healthy {
+				logger.Info("Sidecar is healthy", "endpoint", ep.url)
+			} else {
+				logger.Warn("Sidecar is unhealthy", "endpoint", ep.url, "error", err)
+			}
+		}
+		if healthy {
+			c.checkModel(ctx, ep)
+		}
+	}
+}
+
+func (c *Client) checkHealth(ctx context.Context, ep *endpoint) error {
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, ep.url+"/healthz", nil)
+	if err != nil {
+		return err
+	}
+	response, err := c.http.Do(request)
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("healthz responded %s", response.Status)
+	}
+	return nil
+}
+
+// Ping checks that at least one endpoint answers its health check.
+func (c *Client) Ping(ctx context.Context) error {
+	var errs []error
+	for _, ep := range c.endpoints {
+		err := c.checkHealth(ctx, ep)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", ep.url, err))
+	}
+	return fmt.Errorf("%w: %w", ErrSidecarUnavailable, errors.Join(errs...))
+}
+
+// do runs call against the least loaded available endpoint, retrying
+// transient failures with jittered exponential backoff. The attempts share
+// a single requestTimeout, and one that timed out isn't retried, so a slow
+// sidecar holds a request no longer than one timeout.
+func (c *Client) do(parent context.Context, call func(ctx context.Context, ep *endpoint) error) error {
+	ctx, cancel := context.WithTimeout(parent, requestTimeout)
+	defer cancel()
+
+	var err error
+	var last *endpoint
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			backoff := retryBase << (attempt - 1)
+			select {
+			case <-ctx.Done():
+				if parent.Err() != nil {
+					return parent.Err()
+				}
+				return err
+			case <-time.After(backoff/2 + rand.N(backoff)):
+			}
+		}
+
+		ep, trial := c.pick(last)
+		if ep == nil {
+			return fmt.Errorf("%w: no endpoint available", ErrSidecarUnavailable)
+		}
+		last = ep
+
+		ep.inFlight.Add(1)
+		err = call(ctx, ep)
+		ep.inFlight.Add(-1)
+
+		if parent.Err() != nil {
+			// Says nothing about the endpoint, but a trial must be given back
+			if trial {
+				ep.release()
+			}
+			return parent.Err()
+		}
+		if ctx.Err() != nil || isTimeout(err) {
+			ep.failed(trial)
+			if !errors.Is(err, ErrSidecarUnavailable) {
+				err = fmt.Errorf("%w: %w", ErrSidecarUnavailable, err)
+			}
+			return err
+		}
+		if !errors.Is(err, ErrSidecarUnavailable) {
+			ep.succeeded()
+			return err
+		}
+		ep.failed(trial)
+	}
+	return err
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// pick chooses among the healthy endpoints whose circuit is closed the one
+// with the fewest requests in flight, starting the scan round-robin so ties
+// are spread out. The previous endpoint is avoided when there's a choice.
+// It also reports whether the request is the endpoint's half-open trial.
+func (c *Client) pick(avoid *endpoint) (*endpoint, bool) {
+	start := int(c.next.Add(1))
+	for _, strict := range []bool{true, false} {
+		var best *endpoint
+		for i := range c.endpoints {
+			ep := c.endpoints[(start+i)%len(c.endpoints)]
+			if strict && (!ep.healthy.Load() || ep == avoid) {
+				continue
+			}
+			if !ep.compatible.Load() || !ep.available() {
+				continue
+			}
+			if best == nil || ep.inFlight.Load() < best.inFlight.Load() {
+				best = ep
+			}
+		}
+		if best == nil {
+			continue
+		}
+		if ok, trial := best.allow(); ok {
+			return best, trial
+		}
+	}
+	return nil, false
+}
+
+// available reports whether the circuit breaker would let a request through.
+func (ep *endpoint) available() bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.failures < breakerThreshold || (!ep.trial && !time.Now().Before(ep.openUntil))
+}
+
+// allow is available, but also claims the single trial request of a
+// half-open circuit, reporting whether it did.
+func (ep *endpoint) allow() (ok bool, trial bool) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	if ep.failures < breakerThreshold {
+		return true, false
+	}
+	if time.Now().Before(ep.openUntil) || ep.trial {
+		return false, false
+	}
+	ep.trial = true
+	return true, true
+}
+
+func (ep *endpoint) succeeded() {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.failures = 0
+	ep.trial = false
+}
+
+// release gives back the trial request claimed by allow without counting
+// the request either way. Only the trial request itself may call it.
+func (ep *endpoint) release() {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.trial = false
+}
+
+// failed counts a failed request. A request that started before the
+// circuit opened leaves the trial to the request that holds it.
+func (ep *endpoint) failed(trial bool) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.failures++
+	if trial {
+		ep.trial = false
+	}
+	if ep.failures >= breakerThreshold {
+		if ep.failures == breakerThreshold {
+			logger.Warn("Sidecar circuit open", "endpoint", ep.url, "cooldown", breakerCooldown)
+		}
+		ep.openUntil = time.Now().Add(breakerCooldown)
+	}
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// halfOpen puts the endpoint's circuit where the next request is its trial.
+func halfOpen(ep *endpoint) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.failures = breakerThreshold
+	ep.openUntil = time.Now().Add(-time.Second)
+}
+
+func TestDoReleasesTrialOnCancel(t *testing.T) {
+	client, err := NewClient("http://sidecar.invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ep := client.endpoints[0]
+	halfOpen(ep)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = client.do(ctx, func(ctx context.Context, ep *endpoint) error {
+		cancel()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("do() = %v, want context.Canceled", err)
+	}
+	if !ep.available() {
+		t.Error("the trial wasn't released, so the endpoint stays unavailable")
+	}
+
+	calls := 0
+	err = client.do(context.Background(), func(ctx context.Context, ep *endpoint) error {
+		calls++
+		return nil
+	})
+	if err != nil || calls != 1 {
+		t.Errorf("do() after the cancelled trial = %v after %d call(s), want a successful trial", err, calls)
+	}
+}
+
+func TestDoKeepsAnotherRequestsTrial(t *testing.T) {
+	client, err := NewClient("http://sidecar.invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ep := client.endpoints[0]
+
+	// A request starts while the circuit is closed. Before it ends, the
+	// circuit opens and another request claims the trial.
+	ctx, cancel := context.WithCancel(context.Background())
+	err = client.do(ctx, func(ctx context.Context, ep *endpoint) error {
+		halfOpen(ep)
+		if ok, trial := ep.allow(); !ok || !trial {
+			t.Fatal("the other request didn't get the trial")
+		}
+		cancel()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("do() = %v, want context.Canceled", err)
+	}
+	if ok, _ := ep.allow(); ok {
+		t.Error("the other request's trial was released, letting a second trial through")
+	}
+}
+
+func TestDoDoesNotRetryTimeouts(t *testing.T) {
+	var hits atomic.Int64
+	stop := make(chan struct{})
+	sidecar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-stop
+	}))
+	defer sidecar.Close()
+	defer close(stop)
+
+	client, err := NewClient(sidecar.URL + "," + sidecar.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.http.Timeout = 50 * time.Millisecond
+
+	_, err = client.Embed(context.Background(), []byte("image"))
+	if !errors.Is(err, ErrSidecarUnavailable) {
+		t.Errorf("Embed() = %v, want ErrSidecarUnavailable", err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("the sidecar got %d requests, want 1 as timeouts aren't retried", n)
+	}
+}
+
+func TestDoRetriesUnavailable(t *testing.T) {
+	client, err := NewClient("http://a.invalid,http://b.invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	err = client.do(context.Background(), func(ctx context.Context, ep *endpoint) error {
+		calls++
+		if calls == 1 {
+			return ErrSidecarUnavailable
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("do() = %v after %d call(s), want success on the second", err, calls)
+	}
+}
+
+func TestRequireVerification(t *testing.T) {
+	var hits atomic.Int64
+	sidecar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"embedding": [1, 0], "bbox": [0, 0, 1, 1], "det_score": 0.9}`))
+	}))
+	defer sidecar.Close()
+
+	client, err := NewClient(sidecar.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.RequireVerification()
+
+	if _, err := client.Embed(context.Background(), []byte("image")); !errors.Is(err, ErrModelMismatch) {
+		t.Errorf("Embed() before MarkVerified = %v, want ErrModelMismatch", err)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("the sidecar got %d requests before MarkVerified, want none", n)
+	}
+
+	client.MarkVerified()
+	if _, err := client.Embed(context.Background(), []byte("image")); err != nil {
+		t.Errorf("Embed() after MarkVerified = %v", err)
+	}
+}
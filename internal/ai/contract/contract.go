@@ -0,0 +1,306 @@
+// Package contract checks that a sidecar speaks the HTTP protocol the ai
+// package expects. It runs against the Python sidecar and the fake package
+// alike, so the two can't drift apart unnoticed.
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/imaging"
+)
+
+const requestTimeout = 60 * time.Second
+
+// errSkipped marks a check that couldn't run, e.g. for lack of a face image.
+var errSkipped = errors.New("skipped")
+
+type Result struct {
+	Name string
+	Err  error
+}
+
+func (r Result) Skipped() bool {
+	return errors.Is(r.Err, errSkipped)
+}
+
+type Report struct {
+	Endpoint string
+	Results  []Result
+}
+
+// Passed reports whether no check failed. Skipped checks don't count.
+func (r *Report) Passed() bool {
+	for _, result := range r.Results {
+		if result.Err != nil && !result.Skipped() {
+			return false
+		}
+	}
+	return true
+}
+
+type checker struct {
+	ctx      context.Context
+	http     *http.Client
+	endpoint string
+	info     *ai.ModelInfo
+}
+
+// Verify runs every check against the sidecar at endpoint. face must be an
+// image containing one face; when it's nil, the checks that need one are
+// skipped.
+func Verify(ctx context.Context, endpoint string, face []byte) *Report {
+	c := &checker{
+		ctx:      ctx,
+		http:     &http.Client{Timeout: requestTimeout},
+		endpoint: strings.TrimRight(endpoint, "/"),
+	}
+
+	report := &Report{Endpoint: c.endpoint}
+	run := func(name string, check func() error) {
+		report.Results = append(report.Results, Result{Name: name, Err: check()})
+	}
+
+	run("healthz", c.checkHealth)
+	run("info", c.checkInfo)
+	run("empty file is a bad image", func() error {
+		return c.expectStatus([]byte{}, http.StatusBadRequest)
+	})
+	run("corrupt file is a bad image", func() error {
+		return c.expectStatus([]byte("definitely not an image"), http.StatusBadRequest)
+	})
+	run("blank image has no face", func() error {
+		blank, err := blankJpeg()
+		if err != nil {
+			return err
+		}
+		return c.expectStatus(blank, http.StatusUnprocessableEntity)
+	})
+	run("face is embedded", func() error {
+		return c.checkFace(face)
+	})
+	return report
+}
+
+func (c *checker) checkHealth() error {
+	response, err := c.get("/healthz")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %s", response.Status)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	return nil
+}
+
+func (c *checker) checkInfo() error {
+	response, err := c.get("/info")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %s", response.Status)
+	}
+
+	var info ai.ModelInfo
+	if err := decodeStrict(response.Body, &info); err != nil {
+		return err
+	}
+	switch {
+	case info.Name == "":
+		return fmt.Errorf("name is empty")
+	case info.Version == "":
+		return fmt.Errorf("version is empty")
+	case info.Dim <= 0:
+		return fmt.Errorf("dim is %d", info.Dim)
+	case info.Normalization == "":
+		return fmt.Errorf("normalization is empty")
+	}
+	c.info = &info
+	return nil
+}
+
+// expectStatus checks that the upload is refused with the status and a
+// FastAPI style {"detail": ...} body.
+func (c *checker) expectStatus(imageBytes []byte, status int) error {
+	response, err := c.embed(imageBytes)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != status {
+		return fmt.Errorf("expected status %d, got %s", status, response.Status)
+	}
+
+	var body struct {
+		Detail any `json:"detail"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return fmt.Errorf("decode error body: %w", err)
+	}
+	if body.Detail == nil {
+		return fmt.Errorf("error body has no detail")
+	}
+	return nil
+}
+
+func (c *checker) checkFace(face []byte) error {
+	if face == nil {
+		return fmt.Errorf("%w: no face image given", errSkipped)
+	}
+	if c.info == nil {
+		return fmt.Errorf("%w: no model info", errSkipped)
+	}
+
+	decoded, err := imaging.Decode(face)
+	if err != nil {
+		return fmt.Errorf("face image: %w", err)
+	}
+	bounds := decoded.Frames[0].Bounds()
+
+	first, err := c.embedFace(face)
+	if err != nil {
+		return err
+	}
+
+	if first.Dim != c.info.Dim || len(first.Embedding) != c.info.Dim {
+		return fmt.Errorf("dim is %d with %d values, /info says %d", first.Dim, len(first.Embedding), c.info.Dim)
+	}
+	if c.info.Normalization == "l2" {
+		if norm := l2Norm(first.Embedding); math.Abs(norm-1) > 1e-3 {
+			return fmt.Errorf("embedding norm is %.6f, expected 1", norm)
+		}
+	}
+	if first.DetScore <= 0 || first.DetScore > 1 {
+		return fmt.Errorf("det_score %.4f is outside (0, 1]", first.DetScore)
+	}
+	if err := checkBBox(first.BBox, bounds); err != nil {
+		return err
+	}
+
+	second, err := c.embedFace(face)
+	if err != nil {
+		return err
+	}
+	for i := range first.Embedding {
+		if math.Abs(first.Embedding[i]-second.Embedding[i]) > 1e-4 {
+			return fmt.Errorf("embedding is not deterministic: value %d was %.6f, then %.6f", i, first.Embedding[i], second.Embedding[i])
+		}
+	}
+	return nil
+}
+
+func (c *checker) embedFace(face []byte) (*ai.EmbeddingOkResponse, error) {
+	response, err := c.embed(face)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return nil, fmt.Errorf("status %s: %s", response.Status, bytes.TrimSpace(body))
+	}
+
+	var result ai.EmbeddingOkResponse
+	if err := decodeStrict(response.Body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// checkBBox allows the box to overhang the image a little; detectors
+// extrapolate faces cut off at the edge.
+func checkBBox(bbox []float64, bounds image.Rectangle) error {
+	if len(bbox) != 4 {
+		return fmt.Errorf("bbox has %d values, expected 4", len(bbox))
+	}
+	if bbox[0] >= bbox[2] || bbox[1] >= bbox[3] {
+		return fmt.Errorf("bbox %v is not x1 < x2, y1 < y2", bbox)
+	}
+	marginX := float64(bounds.Dx()) * 0.25
+	marginY := float64(bounds.Dy()) * 0.25
+	if bbox[0] < float64(bounds.Min.X)-marginX || bbox[1] < float64(bounds.Min.Y)-marginY ||
+		bbox[2] > float64(bounds.Max.X)+marginX || bbox[3] > float64(bounds.Max.Y)+marginY {
+		return fmt.Errorf("bbox %v is outside the %dx%d image", bbox, bounds.Dx(), bounds.Dy())
+	}
+	return nil
+}
+
+func (c *checker) get(path string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(c.ctx, http.MethodGet, c.endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.http.Do(request)
+}
+
+func (c *checker) embed(imageBytes []byte) (*http.Response, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "upload.jpg")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(imageBytes); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.endpoint+"/embed-largest-face", &body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return c.http.Do(request)
+}
+
+// decodeStrict refuses fields the Go types don't know about, which would
+// mean the protocol changed on one side only.
+func decodeStrict(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	return nil
+}
+
+func blankJpeg() ([]byte, error) {
+	img := image.NewGray(image.Rect(0, 0, 256, 256))
+	for i := range img.Pix {
+		img.Pix[i] = 128
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func l2Norm(v []float64) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
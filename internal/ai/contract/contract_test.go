@@ -0,0 +1,123 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/ai/fake"
+)
+
+// A real sidecar is checked when SIDECAR_CONTRACT_ENDPOINT is its URL.
+// SIDECAR_CONTRACT_FACE optionally names an image with one face in it, for
+// the embedding checks.
+const (
+	endpointEnv = "SIDECAR_CONTRACT_ENDPOINT"
+	faceEnv     = "SIDECAR_CONTRACT_FACE"
+)
+
+func TestFakeSidecar(t *testing.T) {
+	face := patternJpeg(t)
+	info := ai.ModelInfo{Name: "fake", Version: "1", Dim: 512, Normalization: "l2"}
+
+	tests := []struct {
+		mode    string
+		failing []string // The checks that should fail
+	}{
+		{fake.ModeFace, nil},
+		{fake.ModeNoFace, []string{"face is embedded"}},
+		{fake.ModeLowScore, nil},
+		{fake.ModeBlur, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			sidecar := httptest.NewServer(fake.New(test.mode, info))
+			defer sidecar.Close()
+
+			report := Verify(context.Background(), sidecar.URL, face)
+			failing := map[string]bool{}
+			for _, name := range test.failing {
+				failing[name] = true
+			}
+			for _, result := range report.Results {
+				switch {
+				case result.Skipped():
+					t.Errorf("%s was skipped: %v", result.Name, result.Err)
+				case failing[result.Name] && result.Err == nil:
+					t.Errorf("%s passed, want it to fail", result.Name)
+				case !failing[result.Name] && result.Err != nil:
+					t.Errorf("%s failed: %v", result.Name, result.Err)
+				}
+			}
+			if got, want := report.Passed(), len(test.failing) == 0; got != want {
+				t.Errorf("Passed() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFakeSidecarWithoutFace(t *testing.T) {
+	sidecar := httptest.NewServer(fake.New(fake.ModeFace, ai.ModelInfo{Name: "fake", Version: "1", Dim: 8, Normalization: "l2"}))
+	defer sidecar.Close()
+
+	report := Verify(context.Background(), sidecar.URL, nil)
+	if !report.Passed() {
+		t.Errorf("Passed() = false, want skipped checks not to count: %+v", report.Results)
+	}
+	if last := report.Results[len(report.Results)-1]; !last.Skipped() {
+		t.Errorf("%s = %v, want it skipped without a face", last.Name, last.Err)
+	}
+}
+
+func TestRealSidecar(t *testing.T) {
+	endpoint := os.Getenv(endpointEnv)
+	if endpoint == "" {
+		t.Skipf("%s is not set", endpointEnv)
+	}
+
+	var face []byte
+	if path := os.Getenv(faceEnv); path != "" {
+		var err error
+		face, err = os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report := Verify(context.Background(), endpoint, face)
+	for _, result := range report.Results {
+		switch {
+		case result.Skipped():
+			t.Logf("%s skipped: %v", result.Name, result.Err)
+		case result.Err != nil:
+			t.Errorf("%s failed: %v", result.Name, result.Err)
+		}
+	}
+}
+
+// patternJpeg is an image the fake finds a face in: anything but one flat
+// colour, with a flat corner for the blur mode to pick.
+func patternJpeg(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			c := color.RGBA{R: 128, G: 128, B: 128, A: 255}
+			if x >= 128 || y >= 128 {
+				c = color.RGBA{R: uint8(x ^ y), G: uint8(x), B: uint8(y), A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
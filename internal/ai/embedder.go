@@ -3,16 +3,24 @@ package ai
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"image"
-	"image/color"
-	"math"
 	"mime/multipart"
 	"net/http"
 	"time"
+
+	"github.com/face-match/internal/imaging"
+	"github.com/face-match/internal/metrics"
+	"github.com/face-match/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/face-match/internal/ai")
+
 type errorResponse struct {
 	Detail any `json:"detail"`
 }
@@ -24,167 +32,194 @@ type EmbeddingOkResponse struct {
 	DetScore  float64   `json:"det_score"`
 }
 
-func FetchEmbedding(ctx context.Context, endpoint string, imageBytes []byte) ([]float32, error) {
-	if endpoint == "" {
-		return nil, fmt.Errorf("FetchEmbedding: AIEndpoint is required")
-	}
-	if len(imageBytes) == 0 {
-		return nil, fmt.Errorf("FetchEmbedding: empty image")
-	}
+// Face is the largest face found in an image.
+type Face struct {
+	Embedding []float32
+	BBox      []float64
+	DetScore  float64
+	Quality   *QualityReport
+}
 
-	url := endpoint
-	// tolerate trailing slash
-	if url[len(url)-1] == '/' {
-		url = url[:len(url)-1]
-	}
-	url += "/embed-largest-face"
+// FetchFace embeds the largest face in the upload's working copy. The box is
+// returned in full size coordinates, and the quality checks run on the full
+// size frame. A face that doesn't meet the thresholds is rejected with an
+// *ErrLowQuality holding the full report.
+func (c *Client) FetchFace(ctx context.Context, upload *imaging.Upload, thresholds QualityThresholds) (*Face, error) {
+	ctx, span := tracer.Start(ctx, "ai.FetchFace", trace.WithAttributes(attribute.Int("image.bytes", len(upload.Bytes))))
+	face, err := c.fetchFace(ctx, upload, thresholds)
+	if face != nil {
+		span.SetAttributes(attribute.Float64("face.det_score", face.DetScore))
+	}
+	tracing.End(span, err)
+	return face, err
+}
 
-	request, err := buildRequest(ctx, url, imageBytes)
-	if err != nil {
-		return nil, fmt.Errorf("FetchEmbedding: error building request: %v", err)
+func (c *Client) fetchFace(ctx context.Context, upload *imaging.Upload, thresholds QualityThresholds) (*Face, error) {
+	if len(upload.Bytes) == 0 {
+		return nil, fmt.Errorf("FetchEmbedding: empty image")
 	}
 
-	client := &http.Client{Timeout: 60 * time.Second}
-	response, err := client.Do(request)
+	entry, err := c.lookup(ctx, upload.Bytes)
 	if err != nil {
-		return nil, fmt.Errorf("FetchEmbedding: request failed: %w", err)
+		return nil, err
 	}
-	defer func() { _ = response.Body.Close() }()
+	bbox := upload.ToOriginal(entry.BBox)
 
-	result, err := processResponse(response)
+	report, err := checkFaceQuality(entry.DetScore, bbox, upload.Image, thresholds)
 	if err != nil {
-		return nil, fmt.Errorf("FetchEmbedding: error processing response: %v", err)
+		return nil, fmt.Errorf("FetchEmbedding: error checking face: %w", err)
 	}
-	if _, err := isFoundFaceGood(result, imageBytes); err != nil {
-		return nil, fmt.Errorf("FetchEmbedding: error checking face: %v", err)
+	if err := report.Err(); err != nil {
+		return nil, fmt.Errorf("FetchEmbedding: error checking face: %w", err)
 	}
 
-	embedding := make([]float32, len(result.Embedding))
-	for i, v := range result.Embedding {
-		embedding[i] = float32(v)
-	}
-	return embedding, nil
+	return &Face{Embedding: entry.Embedding, BBox: bbox, DetScore: entry.DetScore, Quality: report}, nil
 }
 
-func buildRequest(ctx context.Context, url string, imageBytes []byte) (*http.Request, error) {
-	var body bytes.Buffer
-	writer := multipart.NewWriter(&body)
-	part, err := writer.CreateFormFile("file", "upload.jpg")
-	if err != nil {
-		return nil, err
-	}
-	if _, err := part.Write(imageBytes); err != nil {
-		return nil, err
+// Embed always asks the sidecar, bypassing the cache and the quality checks.
+// It is meant for comparing the sidecar's current output with earlier output.
+func (c *Client) Embed(ctx context.Context, imageBytes []byte) (*Face, error) {
+	if len(imageBytes) == 0 {
+		return nil, fmt.Errorf("FetchEmbedding: empty image")
 	}
-	if err := writer.Close(); err != nil {
+	if err := c.checkVerified(); err != nil {
 		return nil, err
 	}
 
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	entry, err := c.call(ctx, imageBytes, c.modelID())
 	if err != nil {
-		return nil, fmt.Errorf("FetchEmbedding: new request: %w", err)
+		return nil, err
 	}
-	request.Header.Set("Content-Type", writer.FormDataContentType())
-	return request, nil
+	return &Face{Embedding: entry.Embedding, BBox: entry.BBox, DetScore: entry.DetScore}, nil
 }
 
-func isFoundFaceGood(result *EmbeddingOkResponse, imageBytes []byte) (bool, error) {
-	if result.DetScore < 0.5 {
-		return false, fmt.Errorf("det score of %f is too low", result.DetScore)
+// lookup returns the sidecar's answer for the image, from the cache when
+// possible. Cache failures are logged and otherwise ignored.
+func (c *Client) lookup(ctx context.Context, imageBytes []byte) (*CacheEntry, error) {
+	if err := c.checkVerified(); err != nil {
+		return nil, err
 	}
+	model := c.modelID()
 
-	faceHeight := result.BBox[3] - result.BBox[1]
-	if faceHeight < 92 {
-		return false, fmt.Errorf("face height of %f is too small", faceHeight)
+	key := CacheKey(sha256.Sum256(imageBytes))
+	if c.cache != nil {
+		entry, err := c.cache.Get(ctx, key, model)
+		if err != nil {
+			logger.WarnContext(ctx, "Embedding cache failed", "error", err)
+		}
+		if entry != nil {
+			trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache.hit", true))
+			metrics.EmbeddingCache.WithLabelValues("hit").Inc()
+			return entry, nil
+		}
+		metrics.EmbeddingCache.WithLabelValues("miss").Inc()
 	}
 
-	img, _, err := image.Decode(bytes.NewReader(imageBytes))
+	entry, err := c.call(ctx, imageBytes, model)
 	if err != nil {
-		return false, fmt.Errorf("decode: %v", err)
-	}
-	b := img.Bounds()
-	if b.Empty() {
-		return false, fmt.Errorf("empty image")
+		return nil, err
 	}
 
-	// Clamp bbox to image bounds
-	x1 := int(math.Floor(result.BBox[0]))
-	y1 := int(math.Floor(result.BBox[1]))
-	x2 := int(math.Ceil(result.BBox[2]))
-	y2 := int(math.Ceil(result.BBox[3]))
-	if x1 < b.Min.X {
-		x1 = b.Min.X
-	}
-	if y1 < b.Min.Y {
-		y1 = b.Min.Y
-	}
-	if x2 > b.Max.X {
-		x2 = b.Max.X
-	}
-	if y2 > b.Max.Y {
-		y2 = b.Max.Y
+	if c.cache != nil {
+		if err := c.cache.Put(ctx, key, entry); err != nil {
+			logger.WarnContext(ctx, "Embedding cache failed", "error", err)
+		}
 	}
+	return entry, nil
+}
+
+func (c *Client) modelID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.model
+}
 
-	if x2-x1 < 8 || y2-y1 < 8 {
-		// Too small to blur meaningfully
-		return false, fmt.Errorf("face crop too small (%dx%d)", x2-x1, y2-y1)
+// call sends the image to the sidecar and checks the embedding's size.
+func (c *Client) call(ctx context.Context, imageBytes []byte, model string) (*CacheEntry, error) {
+	var result *EmbeddingOkResponse
+	err := c.do(ctx, func(ctx context.Context, ep *endpoint) error {
+		start := time.Now()
+		var err error
+		result, err = c.embed(ctx, ep, imageBytes)
+		metrics.SidecarDuration.WithLabelValues(ep.url).Observe(time.Since(start).Seconds())
+		if err != nil {
+			metrics.SidecarErrors.WithLabelValues(ep.url, ErrorKind(err)).Inc()
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	crop := image.Rect(x1, y1, x2, y2)
+	if info := c.Info(); info != nil && len(result.Embedding) != info.Dim {
+		return nil, fmt.Errorf("%w: expected %d dimensions, got %d", ErrModelMismatch, info.Dim, len(result.Embedding))
+	}
 
-	w := crop.Dx()
-	h := crop.Dy()
-	gray := make([]float64, w*h)
+	embedding := make([]float32, len(result.Embedding))
+	for i, v := range result.Embedding {
+		embedding[i] = float32(v)
+	}
+	return &CacheEntry{
+		Model:     model,
+		Embedding: embedding,
+		BBox:      result.BBox,
+		DetScore:  result.DetScore,
+	}, nil
+}
 
-	i := 0
-	for y := crop.Min.Y; y < crop.Max.Y; y++ {
-		for x := crop.Min.X; x < crop.Max.X; x++ {
-			gray[i] = float64(luma8(img.At(x, y)))
-			i++
-		}
+func (c *Client) embed(ctx context.Context, ep *endpoint, imageBytes []byte) (*EmbeddingOkResponse, error) {
+	request, err := buildRequest(ctx, ep.url+"/embed-largest-face", imageBytes)
+	if err != nil {
+		return nil, fmt.Errorf("FetchEmbedding: error building request: %w", err)
 	}
 
-	var sum, sumSq float64
-	for y := 1; y < h-1; y++ {
-		row := y * w
-		for x := 1; x < w-1; x++ {
-			c := gray[row+x]
-			l := gray[row+x-1]
-			r := gray[row+x+1]
-			u := gray[row-w+x]
-			d := gray[row+w+x]
-			lap := (-4.0 * c) + l + r + u + d
-			sum += lap
-			sumSq += lap * lap
+	response, err := c.http.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("FetchEmbedding: request failed: %w", ctx.Err())
 		}
+		return nil, fmt.Errorf("FetchEmbedding: request failed: %w: %w", ErrSidecarUnavailable, err)
 	}
-	mean := sum / float64(h*w)
-	variance := (sumSq / float64(h*w)) - (mean * mean)
+	defer func() { _ = response.Body.Close() }()
 
-	if variance < 20 {
-		return false, fmt.Errorf("variance %f is too low", variance)
+	result, err := processResponse(response)
+	if err != nil {
+		return nil, fmt.Errorf("FetchEmbedding: error processing response: %w", err)
 	}
-
-	return true, nil
+	return result, nil
 }
 
-func luma8(c color.Color) uint8 {
-	r, g, b, _ := c.RGBA()
-	R := uint32(r >> 8)
-	G := uint32(g >> 8)
-	B := uint32(b >> 8)
-	Y := (299*R + 587*G + 114*B + 500) / 1000
-	return uint8(Y)
+func buildRequest(ctx context.Context, url string, imageBytes []byte) (*http.Request, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "upload.jpg")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(imageBytes); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return nil, fmt.Errorf("FetchEmbedding: new request: %w", err)
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request, nil
 }
 
 func processResponse(response *http.Response) (*EmbeddingOkResponse, error) {
 	if response.StatusCode < 200 || response.StatusCode >= 300 {
 		var er errorResponse
 		_ = json.NewDecoder(response.Body).Decode(&er)
+		detail := any(response.Status)
 		if er.Detail != nil {
-			return nil, fmt.Errorf("processResponse: sidecar error (%d): %v", response.StatusCode, er.Detail)
+			detail = er.Detail
 		}
-		return nil, fmt.Errorf("processResponse: sidecar error (%d): %s", response.StatusCode, response.Status)
+		return nil, fmt.Errorf("processResponse: sidecar error (%d): %w: %v", response.StatusCode, statusError(response.StatusCode), detail)
 	}
 
 	var result EmbeddingOkResponse
@@ -199,3 +234,17 @@ func processResponse(response *http.Response) (*EmbeddingOkResponse, error) {
 	}
 	return &result, nil
 }
+
+// statusError maps a sidecar status code to one of the package errors.
+func statusError(statusCode int) error {
+	switch {
+	case statusCode == http.StatusUnprocessableEntity:
+		return ErrNoFace
+	case statusCode == http.StatusBadRequest:
+		return ErrBadImage
+	case statusCode >= 500, statusCode == http.StatusTooManyRequests:
+		return ErrSidecarUnavailable
+	default:
+		return errors.New("ai: unexpected sidecar response")
+	}
+}
@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoFace means the sidecar found no face in the image.
+	ErrNoFace = errors.New("ai: no face detected")
+
+	// ErrBadImage means the image could not be decoded.
+	ErrBadImage = errors.New("ai: bad image")
+
+	// ErrSidecarUnavailable means the sidecar could not be reached or failed
+	// with a server error. Retrying later may succeed.
+	ErrSidecarUnavailable = errors.New("ai: sidecar unavailable")
+)
+
+// ErrLowQuality means a face was found, but it failed a quality check. The
+// fields describe the first failed check; Report has all of them.
+type ErrLowQuality struct {
+	Reason    string // det_score, face_height, face_size or sharpness
+	Value     float64
+	Threshold float64
+	Report    *QualityReport
+}
+
+func (e *ErrLowQuality) Error() string {
+	return fmt.Sprintf("ai: low quality face: %s of %.2f is below %.2f", e.Reason, e.Value, e.Threshold)
+}
+
+// ErrorKind names the kind of error for metrics and logs: no_face,
+// bad_image, low_quality_<reason>, model_mismatch, sidecar_unavailable or
+// other.
+func ErrorKind(err error) string {
+	var lowQuality *ErrLowQuality
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &lowQuality):
+		return "low_quality_" + lowQuality.Reason
+	case errors.Is(err, ErrNoFace):
+		return "no_face"
+	case errors.Is(err, ErrBadImage):
+		return "bad_image"
+	case errors.Is(err, ErrModelMismatch):
+		return "model_mismatch"
+	case errors.Is(err, ErrSidecarUnavailable):
+		return "sidecar_unavailable"
+	default:
+		return "other"
+	}
+}
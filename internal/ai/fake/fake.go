@@ -0,0 +1,220 @@
+// Package fake serves the AI sidecar's HTTP contract without InsightFace,
+// for cmd/fake-sidecar and for tests.
+//
+// Every image that isn't a single flat colour has exactly one "face", centred
+// in the image. Its embedding is derived from the image's dHash, so the same
+// picture always embeds the same way, and re-encoded or resized copies of it
+// usually do too. Different pictures get unrelated embeddings.
+//
+// The mode, or an X-Fake-Mode request header, makes the fake answer like the
+// real sidecar does for bad input:
+//
+//	face       the default
+//	no-face    422 "No face detected" for every image
+//	low-score  a face with a det_score of 0.3
+//	blur       a face placed over the flattest part of the image
+package fake
+
+import (
+	"encoding/json"
+	"image"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/hash"
+	"github.com/face-match/internal/imaging"
+)
+
+const (
+	ModeFace     = "face"
+	ModeNoFace   = "no-face"
+	ModeLowScore = "low-score"
+	ModeBlur     = "blur"
+
+	detScore    = 0.99
+	lowDetScore = 0.3
+
+	// The largest upload read, matching what the server accepts.
+	maxUpload = 32 << 20
+)
+
+type fakeSidecar struct {
+	mode string
+	info ai.ModelInfo
+}
+
+// New returns a handler serving /embed-largest-face, /info and /healthz,
+// answering in mode and reporting info as its model.
+func New(mode string, info ai.ModelInfo) http.Handler {
+	fake := &fakeSidecar{mode: mode, info: info}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /embed-largest-face", fake.handleEmbed)
+	mux.HandleFunc("GET /info", fake.handleInfo)
+	mux.HandleFunc("GET /healthz", fake.handleHealth)
+	return mux
+}
+
+// ValidMode reports whether mode is one of the modes above.
+func ValidMode(mode string) bool {
+	switch mode {
+	case ModeFace, ModeNoFace, ModeLowScore, ModeBlur:
+		return true
+	}
+	return false
+}
+
+func (fake *fakeSidecar) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	mode := fake.mode
+	if m := r.Header.Get("X-Fake-Mode"); m != "" {
+		if !ValidMode(m) {
+			writeDetail(w, http.StatusBadRequest, "Unknown X-Fake-Mode: "+m)
+			return
+		}
+		mode = m
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeDetail(w, http.StatusUnprocessableEntity, "Missing file field")
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxUpload))
+	if err != nil {
+		writeDetail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(data) == 0 {
+		writeDetail(w, http.StatusBadRequest, "Empty file")
+		return
+	}
+
+	decoded, err := imaging.Decode(data)
+	if err != nil {
+		writeDetail(w, http.StatusBadRequest, "Failed to decode image (unsupported format or corrupt file).")
+		return
+	}
+	img := decoded.First()
+
+	if mode == ModeNoFace || isFlat(img) {
+		writeDetail(w, http.StatusUnprocessableEntity, "No face detected")
+		return
+	}
+
+	response := ai.EmbeddingOkResponse{
+		Embedding: fake.embedding(img),
+		Dim:       fake.info.Dim,
+		BBox:      centredBox(img.Bounds()),
+		DetScore:  detScore,
+	}
+	switch mode {
+	case ModeLowScore:
+		response.DetScore = lowDetScore
+	case ModeBlur:
+		response.BBox = flattestBox(img)
+	}
+
+	writeJson(w, http.StatusOK, response)
+}
+
+func (fake *fakeSidecar) handleInfo(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, fake.info)
+}
+
+func (fake *fakeSidecar) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, map[string]string{"status": "okay"})
+}
+
+// embedding is a unit vector seeded by the image's dHash.
+func (fake *fakeSidecar) embedding(img image.Image) []float64 {
+	seed := uint64(hash.DHash64FromImage(img))
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+
+	embedding := make([]float64, fake.info.Dim)
+	var norm float64
+	for i := range embedding {
+		embedding[i] = rng.NormFloat64()
+		norm += embedding[i] * embedding[i]
+	}
+	norm = math.Sqrt(norm)
+	for i := range embedding {
+		embedding[i] /= norm
+	}
+	return embedding
+}
+
+// centredBox covers the middle of the image, like a tightly framed portrait.
+func centredBox(b image.Rectangle) []float64 {
+	w := float64(b.Dx())
+	h := float64(b.Dy())
+	return []float64{
+		float64(b.Min.X) + w*0.25,
+		float64(b.Min.Y) + h*0.15,
+		float64(b.Min.X) + w*0.75,
+		float64(b.Min.Y) + h*0.85,
+	}
+}
+
+// flattestBox returns the quarter of the image with the least contrast, so
+// the client's sharpness check sees a blurry face.
+func flattestBox(img image.Image) []float64 {
+	b := img.Bounds()
+	halfW := b.Dx() / 2
+	halfH := b.Dy() / 2
+
+	var best image.Rectangle
+	bestSpread := math.Inf(1)
+	for _, corner := range []image.Point{{0, 0}, {halfW, 0}, {0, halfH}, {halfW, halfH}} {
+		r := image.Rect(0, 0, halfW, halfH).Add(b.Min).Add(corner)
+		if spread := lumaSpread(img, r); spread < bestSpread {
+			best = r
+			bestSpread = spread
+		}
+	}
+	return []float64{float64(best.Min.X), float64(best.Min.Y), float64(best.Max.X), float64(best.Max.Y)}
+}
+
+// isFlat reports whether the image is a single colour; the real detector
+// finds no face in those.
+func isFlat(img image.Image) bool {
+	return lumaSpread(img, img.Bounds()) == 0
+}
+
+// lumaSpread is the difference between the brightest and darkest sampled
+// pixels of r.
+func lumaSpread(img image.Image, r image.Rectangle) float64 {
+	if r.Empty() {
+		return 0
+	}
+	stepX := max(1, r.Dx()/64)
+	stepY := max(1, r.Dy()/64)
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for y := r.Min.Y; y < r.Max.Y; y += stepY {
+		for x := r.Min.X; x < r.Max.X; x += stepX {
+			cr, cg, cb, _ := img.At(x, y).RGBA()
+			l := 0.299*float64(cr) + 0.587*float64(cg) + 0.114*float64(cb)
+			lo = math.Min(lo, l)
+			hi = math.Max(hi, l)
+		}
+	}
+	return hi - lo
+}
+
+func writeDetail(w http.ResponseWriter, status int, detail string) {
+	writeJson(w, status, map[string]string{"detail": detail})
+}
+
+func writeJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Writing the response failed", "error", err)
+	}
+}
@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrModelMismatch means embeddings from the sidecar can't be compared with
+// the ones already stored.
+var ErrModelMismatch = errors.New("ai: model mismatch")
+
+// ModelInfo identifies the model a sidecar embeds with.
+type ModelInfo struct {
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	Dim           int    `json:"dim"`
+	Normalization string `json:"normalization"`
+}
+
+// ID is the model's identity in cache keys.
+func (m ModelInfo) ID() string {
+	return m.Name + "@" + m.Version
+}
+
+// Handshake fetches the model identity from every endpoint and checks that
+// they all agree. Later probes take any endpoint that starts reporting a
+// different model out of rotation.
+func (c *Client) Handshake(ctx context.Context) (*ModelInfo, error) {
+	var info *ModelInfo
+	for _, ep := range c.endpoints {
+		epInfo, err := c.fetchInfo(ctx, ep)
+		if err != nil {
+			return nil, fmt.Errorf("ai: %s: %w", ep.url, err)
+		}
+		if info != nil && *epInfo != *info {
+			return nil, fmt.Errorf("%w: %s reports %s, others report %s", ErrModelMismatch, ep.url, epInfo.ID(), info.ID())
+		}
+		info = epInfo
+	}
+
+	c.mu.Lock()
+	c.info = info
+	c.model = info.ID()
+	c.mu.Unlock()
+	return info, nil
+}
+
+// Info returns the model found by Handshake, or nil before it has run.
+func (c *Client) Info() *ModelInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.info
+}
+
+func (c *Client) fetchInfo(ctx context.Context, ep *endpoint) (*ModelInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, ep.url+"/info", nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSidecarUnavailable, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: info responded %s", ErrSidecarUnavailable, response.Status)
+	}
+
+	var info ModelInfo
+	if err := json.NewDecoder(response.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("decode info: %w", err)
+	}
+	if info.Name == "" || info.Dim <= 0 {
+		return nil, fmt.Errorf("incomplete model info: %+v", info)
+	}
+	return &info, nil
+}
+
+// Ready checks that at least one endpoint is healthy and still reports the
+// model found by Handshake.
+func (c *Client) Ready(ctx context.Context) error {
+	expected := c.Info()
+	if expected == nil {
+		return fmt.Errorf("%w: no handshake yet", ErrModelMismatch)
+	}
+
+	var errs []error
+	mismatch := false
+	for _, ep := range c.endpoints {
+		if err := c.checkHealth(ctx, ep); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ep.url, err))
+			continue
+		}
+		info, err := c.fetchInfo(ctx, ep)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ep.url, err))
+			continue
+		}
+		if *info != *expected {
+			mismatch = true
+			errs = append(errs, fmt.Errorf("%s reports %s, expected %s", ep.url, info.ID(), expected.ID()))
+			continue
+		}
+		return nil
+	}
+	if mismatch {
+		return fmt.Errorf("%w: %w", ErrModelMismatch, errors.Join(errs...))
+	}
+	return fmt.Errorf("%w: %w", ErrSidecarUnavailable, errors.Join(errs...))
+}
+
+// checkModel takes an endpoint out of rotation while it reports a different
+// model than the handshake found.
+func (c *Client) checkModel(ctx context.Context, ep *endpoint) {
+	expected := c.Info()
+	if expected == nil {
+		return
+	}
+	info, err := c.fetchInfo(ctx, ep)
+	if err != nil {
+		return
+	}
+	compatible := *info == *expected
+	if ep.compatible.Swap(compatible) != compatible && !compatible {
+		logger.Warn("Sidecar reports a different model", "endpoint", ep.url, "model", info.ID(), "expected", expected.ID())
+	}
+}
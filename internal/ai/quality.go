@@ -0,0 +1,239 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"os"
+
+	"github.com/face-match/internal/imaging"
+)
+
+// QualityThresholds are the minimums a detected face must meet. A zero
+// threshold disables its check.
+type QualityThresholds struct {
+	MinDetScore   float64 `json:"min_det_score"`
+	MinFaceHeight float64 `json:"min_face_height"` // In pixels
+	MinSharpness  float64 `json:"min_sharpness"`   // Variance of the Laplacian
+}
+
+// QualityProfiles holds separate thresholds for enrolling faces into the
+// database and for the faces people search with.
+type QualityProfiles struct {
+	Enrollment QualityThresholds `json:"enrollment"`
+	Query      QualityThresholds `json:"query"`
+}
+
+// QualityOverride replaces the thresholds it sets, including with zero to
+// disable a check. Unset ones are nil.
+type QualityOverride struct {
+	MinDetScore   *float64 `json:"min_det_score"`
+	MinFaceHeight *float64 `json:"min_face_height"`
+	MinSharpness  *float64 `json:"min_sharpness"`
+}
+
+// QualityOverrides holds a category's overrides of both profiles.
+type QualityOverrides struct {
+	Enrollment QualityOverride `json:"enrollment"`
+	Query      QualityOverride `json:"query"`
+}
+
+// QualityPolicy is the default profiles plus overrides by category display
+// name. Overrides only replace the thresholds they set.
+type QualityPolicy struct {
+	QualityProfiles
+	Categories map[string]QualityOverrides `json:"categories"`
+}
+
+func DefaultQualityPolicy() QualityPolicy {
+	defaults := QualityThresholds{
+		MinDetScore:   0.5,
+		MinFaceHeight: 92,
+		MinSharpness:  20,
+	}
+	return QualityPolicy{
+		QualityProfiles: QualityProfiles{
+			Enrollment: defaults,
+			Query:      defaults,
+		},
+	}
+}
+
+// LoadQualityPolicy reads a JSON policy file. Thresholds missing from the
+// file keep their defaults.
+func LoadQualityPolicy(path string) (QualityPolicy, error) {
+	policy := DefaultQualityPolicy()
+	if path == "" {
+		return policy, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, fmt.Errorf("ai: read quality policy: %w", err)
+	}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return policy, fmt.Errorf("ai: parse quality policy: %w", err)
+	}
+	return policy, nil
+}
+
+// EnrollmentFor returns the thresholds for adding a face to the category.
+func (p QualityPolicy) EnrollmentFor(category string) QualityThresholds {
+	if override, ok := p.Categories[category]; ok {
+		return p.Enrollment.merge(override.Enrollment)
+	}
+	return p.Enrollment
+}
+
+// QueryFor returns the thresholds for searching the categories. When they
+// disagree, the most lenient value of each threshold wins.
+func (p QualityPolicy) QueryFor(categories []string) QualityThresholds {
+	var out *QualityThresholds
+	for _, category := range categories {
+		t := p.Query
+		if override, ok := p.Categories[category]; ok {
+			t = t.merge(override.Query)
+		}
+		if out == nil {
+			out = &t
+			continue
+		}
+		out.MinDetScore = min(out.MinDetScore, t.MinDetScore)
+		out.MinFaceHeight = min(out.MinFaceHeight, t.MinFaceHeight)
+		out.MinSharpness = min(out.MinSharpness, t.MinSharpness)
+	}
+	if out == nil {
+		return p.Query
+	}
+	return *out
+}
+
+func (t QualityThresholds) merge(override QualityOverride) QualityThresholds {
+	if override.MinDetScore != nil {
+		t.MinDetScore = *override.MinDetScore
+	}
+	if override.MinFaceHeight != nil {
+		t.MinFaceHeight = *override.MinFaceHeight
+	}
+	if override.MinSharpness != nil {
+		t.MinSharpness = *override.MinSharpness
+	}
+	return t
+}
+
+type QualityCheck struct {
+	Name      string  `json:"name"` // det_score, face_height, face_size or sharpness
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Passed    bool    `json:"passed"`
+}
+
+// QualityReport holds the outcome of every check, not just the first failure.
+type QualityReport struct {
+	Checks []QualityCheck `json:"checks"`
+}
+
+func (r *QualityReport) add(name string, value float64, threshold float64) {
+	r.Checks = append(r.Checks, QualityCheck{
+		Name:      name,
+		Value:     value,
+		Threshold: threshold,
+		Passed:    value >= threshold,
+	})
+}
+
+func (r *QualityReport) Passed() bool {
+	for _, c := range r.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns an *ErrLowQuality for the first failed check, or nil.
+func (r *QualityReport) Err() error {
+	for _, c := range r.Checks {
+		if !c.Passed {
+			return &ErrLowQuality{Reason: c.Name, Value: c.Value, Threshold: c.Threshold, Report: r}
+		}
+	}
+	return nil
+}
+
+// checkFaceQuality runs every quality check on the detected face. An error is
+// only returned when the checks can't run at all.
+func checkFaceQuality(detScore float64, bbox []float64, img image.Image, thresholds QualityThresholds) (*QualityReport, error) {
+	if len(bbox) != 4 {
+		return nil, fmt.Errorf("invalid bbox: %v", bbox)
+	}
+
+	report := &QualityReport{}
+	report.add("det_score", detScore, thresholds.MinDetScore)
+	report.add("face_height", bbox[3]-bbox[1], thresholds.MinFaceHeight)
+
+	if thresholds.MinSharpness <= 0 {
+		return report, nil
+	}
+
+	b := img.Bounds()
+	if b.Empty() {
+		return nil, fmt.Errorf("empty image: %w", ErrBadImage)
+	}
+
+	// Clamp bbox to image bounds
+	x1 := int(math.Floor(bbox[0]))
+	y1 := int(math.Floor(bbox[1]))
+	x2 := int(math.Ceil(bbox[2]))
+	y2 := int(math.Ceil(bbox[3]))
+	if x1 < b.Min.X {
+		x1 = b.Min.X
+	}
+	if y1 < b.Min.Y {
+		y1 = b.Min.Y
+	}
+	if x2 > b.Max.X {
+		x2 = b.Max.X
+	}
+	if y2 > b.Max.Y {
+		y2 = b.Max.Y
+	}
+
+	if x2-x1 < 8 || y2-y1 < 8 {
+		// Too small to blur meaningfully
+		report.add("face_size", float64(min(x2-x1, y2-y1)), 8)
+		return report, nil
+	}
+
+	report.add("sharpness", laplacianVariance(img, image.Rect(x1, y1, x2, y2)), thresholds.MinSharpness)
+	return report, nil
+}
+
+// laplacianVariance measures sharpness; blurry crops have little variance.
+func laplacianVariance(img image.Image, crop image.Rectangle) float64 {
+	w := crop.Dx()
+	h := crop.Dy()
+	luma := imaging.Luma(img, crop)
+	gray := make([]float64, len(luma))
+	for i, v := range luma {
+		gray[i] = float64(v)
+	}
+
+	var sum, sumSq float64
+	for y := 1; y < h-1; y++ {
+		row := y * w
+		for x := 1; x < w-1; x++ {
+			c := gray[row+x]
+			l := gray[row+x-1]
+			r := gray[row+x+1]
+			u := gray[row-w+x]
+			d := gray[row+w+x]
+			lap := (-4.0 * c) + l + r + u + d
+			sum += lap
+			sumSq += lap * lap
+		}
+	}
+	mean := sum / float64(h*w)
+	return (sumSq / float64(h*w)) - (mean * mean)
+}
@@ -0,0 +1,56 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadQualityPolicyOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quality.json")
+	policy := `{
+		"query": {"min_face_height": 48},
+		"categories": {
+			"Archive": {"query": {"min_sharpness": 0, "min_det_score": 0.3}},
+			"Studio": {"enrollment": {"min_face_height": 128}}
+		}
+	}`
+	if err := os.WriteFile(path, []byte(policy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadQualityPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := DefaultQualityPolicy()
+	if got, want := p.QueryFor([]string{"Other"}), (QualityThresholds{MinDetScore: 0.5, MinFaceHeight: 48, MinSharpness: 20}); got != want {
+		t.Errorf("QueryFor(Other) = %+v, want %+v", got, want)
+	}
+	if got, want := p.QueryFor([]string{"Archive"}), (QualityThresholds{MinDetScore: 0.3, MinFaceHeight: 48, MinSharpness: 0}); got != want {
+		t.Errorf("QueryFor(Archive) = %+v, want %+v, as an explicit 0 disables the check", got, want)
+	}
+	if got, want := p.EnrollmentFor("Studio"), (QualityThresholds{MinDetScore: 0.5, MinFaceHeight: 128, MinSharpness: 20}); got != want {
+		t.Errorf("EnrollmentFor(Studio) = %+v, want %+v", got, want)
+	}
+	if got := p.EnrollmentFor("Archive"); got != defaults.Enrollment {
+		t.Errorf("EnrollmentFor(Archive) = %+v, want the defaults %+v", got, defaults.Enrollment)
+	}
+}
+
+func TestQueryForMostLenient(t *testing.T) {
+	zero := 0.0
+	height := 200.0
+	p := DefaultQualityPolicy()
+	p.Categories = map[string]QualityOverrides{
+		"A": {Query: QualityOverride{MinSharpness: &zero}},
+		"B": {Query: QualityOverride{MinFaceHeight: &height}},
+	}
+
+	got := p.QueryFor([]string{"A", "B"})
+	want := QualityThresholds{MinDetScore: 0.5, MinFaceHeight: 92, MinSharpness: 0}
+	if got != want {
+		t.Errorf("QueryFor(A, B) = %+v, want %+v", got, want)
+	}
+}
@@ -1,12 +1,101 @@
 package app
 
+import (
+	"net/netip"
+	"time"
+
+	"github.com/face-match/internal/ai"
+)
+
+// Query retention policies.
+const (
+	// Query images and embeddings are dropped once the search is answered.
+	RetentionNone = "none"
+
+	// Query embeddings may be cached for up to the TTL; images are dropped
+	// once searched.
+	RetentionEmbeddingOnly = "embedding-only"
+
+	// Search job images are kept, along with the job, for the TTL.
+	RetentionFull = "full"
+)
+
 type Config struct {
 	AIEndpoint  string
 	DatabaseUrl string
 	DataRoot    string
 	WebEndpoint string
 
+	// Address the gRPC API listens on. Empty disables it.
+	GrpcEndpoint string
+
+	// Directory the web UI is served from instead of the built-in files,
+	// re-read on every request. Meant for working on the UI.
+	WebDir string
+
+	// Command used to extract frames from videos the native decoders don't
+	// support, e.g. "ffmpeg". Empty disables it.
+	FrameExtractor string
+
+	// Path of an optional JSON file with face quality thresholds.
+	QualityPolicyPath string
+
+	// Log output format, "text" (the default) or "json".
+	LogFormat string
+
+	// Default log level optionally followed by per-subsystem levels, e.g.
+	// "info,ai=debug".
+	LogLevel string
+
+	// OTLP/HTTP collector traces are exported to. Empty disables tracing.
+	OtlpEndpoint string
+
+	// Share of new traces that are recorded, from 0 to 1.
+	TraceSampleRatio float64
+
+	// HTTP server timeouts. Zero means no timeout.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+
+	// Origins allowed to call the API from a browser, or "*" for any.
+	CorsOrigins []string
+
+	// Networks search job webhooks may reach even though they're loopback,
+	// private or link-local, e.g. an internal service's subnet.
+	WebhookAllowedNetworks []netip.Prefix
+
+	// Bearer token required by /api/v1/admin. Admin endpoints are disabled
+	// when empty.
+	AdminToken string
+
+	// Keeps uploaded queries off disk: multipart forms stay in memory and
+	// search jobs, which store their images in the database, are disabled.
+	PrivacyMode bool
+
+	// What is kept of search queries: RetentionNone, RetentionEmbeddingOnly
+	// or RetentionFull. Finished search jobs are deleted after
+	// QueryRetentionTTL in every case.
+	QueryRetention    string
+	QueryRetentionTTL time.Duration
+
+	// Largest request body the server reads, in bytes.
+	MaxUploadSize int64
+
+	// The number of background workers processing search jobs.
+	JobWorkers int
+
+	// The number of sidecar calls a single batch search may have in flight.
+	BatchConcurrency int
+
+	// The number of query embeddings the server keeps in memory.
+	EmbeddingCacheSize int
+
+	// How often the canary images are re-embedded to detect drift.
+	CanaryInterval time.Duration
+
 	// Calculated
+	Quality      ai.QualityPolicy
 	InputPath    string
 	FinishedPath string
 	ThumbsPath   string
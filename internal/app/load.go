@@ -0,0 +1,501 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/logging"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the environment variable holding the config file path
+// when --config isn't given.
+const ConfigFileEnv = "CONFIG_FILE"
+
+// setting is one configuration value, which can be set in the config file,
+// from the environment or with a flag.
+type setting struct {
+	key   string // in the config file; the flag is the same in kebab case
+	env   string
+	usage string
+	set   func(*Config, string) error
+	get   func(*Config) string
+
+	// Replaces the value when printed; set for secrets.
+	redact func(string) string
+
+	// The flag may be given without a value, meaning true.
+	boolean bool
+}
+
+func (s *setting) flag() string {
+	return strings.ReplaceAll(s.key, "_", "-")
+}
+
+// settings lists everything that can be configured, in the order
+// --print-config shows it.
+var settings = []setting{
+	stringSetting("ai_endpoint", "AI_ENDPOINT", "Sidecar URL, or a comma separated list", func(c *Config) *string { return &c.AIEndpoint }),
+	withRedact(stringSetting("database_url", "DATABASE_URL", "Database URL", func(c *Config) *string { return &c.DatabaseUrl }), redactDatabaseUrl),
+	stringSetting("data_root", "DATA_ROOT", "Data root directory", func(c *Config) *string { return &c.DataRoot }),
+	stringSetting("web_endpoint", "WEB_ENDPOINT", "Address the server listens on", func(c *Config) *string { return &c.WebEndpoint }),
+	stringSetting("grpc_endpoint", "GRPC_ENDPOINT", "Address the gRPC API listens on, e.g. localhost:9090; off when empty", func(c *Config) *string { return &c.GrpcEndpoint }),
+	stringSetting("web_dir", "WEB_DIR", "Serve the web UI from this directory instead of the built-in files, e.g. web/static", func(c *Config) *string { return &c.WebDir }),
+	stringSetting("frame_extractor", "FRAME_EXTRACTOR", "Command extracting frames from other video formats, e.g. ffmpeg", func(c *Config) *string { return &c.FrameExtractor }),
+	stringSetting("quality_policy", "QUALITY_POLICY", "JSON file with face quality thresholds", func(c *Config) *string { return &c.QualityPolicyPath }),
+	stringSetting("log_format", "LOG_FORMAT", "Log format: text or json", func(c *Config) *string { return &c.LogFormat }),
+	stringSetting("log_level", "LOG_LEVEL", "Log level, optionally per subsystem, e.g. info,import=debug", func(c *Config) *string { return &c.LogLevel }),
+	stringSetting("otlp_endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTLP/HTTP collector URL traces are sent to", func(c *Config) *string { return &c.OtlpEndpoint }),
+	{
+		key:   "trace_sample_ratio",
+		env:   "TRACE_SAMPLE_RATIO",
+		usage: "Share of new traces that are recorded, from 0 to 1",
+		set: func(c *Config, value string) error {
+			ratio, err := strconv.ParseFloat(value, 64)
+			if err != nil || ratio < 0 || ratio > 1 {
+				return fmt.Errorf("invalid ratio %q: must be between 0 and 1", value)
+			}
+			c.TraceSampleRatio = ratio
+			return nil
+		},
+		get: func(c *Config) string { return strconv.FormatFloat(c.TraceSampleRatio, 'g', -1, 64) },
+	},
+	durationSetting("read_timeout", "READ_TIMEOUT", "Time allowed to read a request, 0 for none", 0, func(c *Config) *time.Duration { return &c.ReadTimeout }),
+	durationSetting("write_timeout", "WRITE_TIMEOUT", "Time allowed to handle a request and write the response, 0 for none", 0, func(c *Config) *time.Duration { return &c.WriteTimeout }),
+	durationSetting("idle_timeout", "IDLE_TIMEOUT", "Time an idle keep-alive connection is kept open, 0 for none", 0, func(c *Config) *time.Duration { return &c.IdleTimeout }),
+	{
+		key:   "cors_origins",
+		env:   "CORS_ORIGINS",
+		usage: "Comma separated origins allowed to call the API from a browser, or * for any",
+		set: func(c *Config, value string) error {
+			var origins []string
+			for _, origin := range strings.Split(value, ",") {
+				origin = strings.TrimSpace(origin)
+				if origin == "" {
+					continue
+				}
+				if origin != "*" {
+					u, err := url.Parse(origin)
+					if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+						return fmt.Errorf("invalid origin %q: use e.g. https://example.com", origin)
+					}
+				}
+				origins = append(origins, origin)
+			}
+			c.CorsOrigins = origins
+			return nil
+		},
+		get: func(c *Config) string { return strings.Join(c.CorsOrigins, ",") },
+	},
+	{
+		key:   "webhook_allowed_networks",
+		env:   "WEBHOOK_ALLOWED_NETWORKS",
+		usage: "Comma separated private networks search job webhooks may call, e.g. 10.1.0.0/16",
+		set: func(c *Config, value string) error {
+			var networks []netip.Prefix
+			for _, network := range strings.Split(value, ",") {
+				network = strings.TrimSpace(network)
+				if network == "" {
+					continue
+				}
+				prefix, err := netip.ParsePrefix(network)
+				if err != nil {
+					addr, addrErr := netip.ParseAddr(network)
+					if addrErr != nil {
+						return fmt.Errorf("invalid network %q: use e.g. 10.1.0.0/16", network)
+					}
+					prefix = netip.PrefixFrom(addr, addr.BitLen())
+				}
+				networks = append(networks, prefix.Masked())
+			}
+			c.WebhookAllowedNetworks = networks
+			return nil
+		},
+		get: func(c *Config) string {
+			networks := make([]string, 0, len(c.WebhookAllowedNetworks))
+			for _, prefix := range c.WebhookAllowedNetworks {
+				networks = append(networks, prefix.String())
+			}
+			return strings.Join(networks, ",")
+		},
+	},
+	withRedact(stringSetting("admin_token", "ADMIN_TOKEN", "Bearer token for the admin API, which is disabled without one", func(c *Config) *string { return &c.AdminToken }), redactAll),
+	boolSetting("privacy_mode", "PRIVACY_MODE", "Keep uploaded queries off disk, disabling search jobs", func(c *Config) *bool { return &c.PrivacyMode }),
+	{
+		key:   "query_retention",
+		env:   "QUERY_RETENTION",
+		usage: "What is kept of search queries: none, embedding-only or full",
+		set: func(c *Config, value string) error {
+			switch value {
+			case RetentionNone, RetentionEmbeddingOnly, RetentionFull:
+				c.QueryRetention = value
+				return nil
+			}
+			return fmt.Errorf("unknown policy %q: use none, embedding-only or full", value)
+		},
+		get: func(c *Config) string { return c.QueryRetention },
+	},
+	durationSetting("query_retention_ttl", "QUERY_RETENTION_TTL", "How long retained queries and finished search jobs are kept", time.Minute, func(c *Config) *time.Duration { return &c.QueryRetentionTTL }),
+	{
+		key:   "max_upload_size",
+		env:   "MAX_UPLOAD_SIZE",
+		usage: "Largest request body accepted, e.g. 32MB",
+		set: func(c *Config, value string) error {
+			size, err := parseSize(value)
+			if err != nil {
+				return err
+			}
+			c.MaxUploadSize = size
+			return nil
+		},
+		get: func(c *Config) string { return formatSize(c.MaxUploadSize) },
+	},
+	intSetting("job_workers", "JOB_WORKERS", "Background workers processing search jobs", func(c *Config) *int { return &c.JobWorkers }),
+	intSetting("batch_concurrency", "BATCH_CONCURRENCY", "Sidecar calls a batch search may have in flight", func(c *Config) *int { return &c.BatchConcurrency }),
+	intSetting("embedding_cache_size", "EMBEDDING_CACHE_SIZE", "Query embeddings kept in memory", func(c *Config) *int { return &c.EmbeddingCacheSize }),
+	durationSetting("canary_interval", "CANARY_INTERVAL", "How often the canary images are checked for drift", time.Second, func(c *Config) *time.Duration { return &c.CanaryInterval }),
+}
+
+// Default returns the configuration used for anything that isn't set.
+func Default() *Config {
+	return &Config{
+		DataRoot:           "data",
+		WebEndpoint:        "localhost:8080",
+		LogFormat:          "text",
+		LogLevel:           "info",
+		TraceSampleRatio:   1,
+		ReadTimeout:        15 * time.Second,
+		WriteTimeout:       30 * time.Second,
+		IdleTimeout:        60 * time.Second,
+		QueryRetention:     RetentionEmbeddingOnly,
+		QueryRetentionTTL:  24 * time.Hour,
+		MaxUploadSize:      32 << 20,
+		JobWorkers:         2,
+		BatchConcurrency:   4,
+		EmbeddingCacheSize: 1024,
+		CanaryInterval:     time.Hour,
+	}
+}
+
+// RegisterFlags adds a flag for every setting to fs, plus --config and
+// --print-config.
+func RegisterFlags(fs *pflag.FlagSet) {
+	defaults := Default()
+	fs.String("config", "", "YAML or TOML config file (env "+ConfigFileEnv+")")
+	fs.Bool("print-config", false, "Print the effective configuration, with secrets redacted, and exit")
+	for i := range settings {
+		s := &settings[i]
+		fs.String(s.flag(), s.get(defaults), s.usage+" (env "+s.env+")")
+		if s.boolean {
+			fs.Lookup(s.flag()).NoOptDefVal = "true"
+		}
+	}
+}
+
+// PrintRequested reports whether --print-config was given.
+func PrintRequested(fs *pflag.FlagSet) bool {
+	requested, _ := fs.GetBool("print-config")
+	return requested
+}
+
+// LoadConfig builds the configuration from, in increasing precedence, the
+// defaults, the config file, the environment and the flags registered with
+// RegisterFlags. Settings named in required must not be empty. Every invalid
+// setting is reported, along with where its value came from.
+func LoadConfig(fs *pflag.FlagSet, required ...string) (*Config, error) {
+	config := Default()
+	var errs []error
+	apply := func(s *setting, value string, source string) {
+		if err := s.set(config, value); err != nil {
+			errs = append(errs, fmt.Errorf("config: %s (from %s): %w", s.key, source, err))
+		}
+	}
+
+	path := os.Getenv(ConfigFileEnv)
+	if f := fs.Lookup("config"); f != nil && f.Changed {
+		path = f.Value.String()
+	}
+	if path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			s := lookupSetting(key)
+			if s == nil {
+				errs = append(errs, fmt.Errorf("config: unknown setting %q in %s", key, path))
+				continue
+			}
+			apply(s, values[key], path)
+		}
+	}
+
+	for i := range settings {
+		s := &settings[i]
+		// A variable that is set but empty still counts, e.g. to clear
+		// a string set in the file
+		if value, ok := os.LookupEnv(s.env); ok {
+			apply(s, value, s.env)
+		}
+	}
+
+	for i := range settings {
+		s := &settings[i]
+		if f := fs.Lookup(s.flag()); f != nil && f.Changed {
+			apply(s, f.Value.String(), "--"+s.flag())
+		}
+	}
+
+	for _, key := range required {
+		s := lookupSetting(key)
+		if s.get(config) == "" {
+			errs = append(errs, fmt.Errorf("config: %s is required: set %s, --%s or %s in the config file", s.key, s.env, s.flag(), s.key))
+		}
+	}
+	if config.PrivacyMode && config.QueryRetention == RetentionFull {
+		errs = append(errs, fmt.Errorf("config: query_retention %s keeps uploads in the database, which privacy_mode forbids", RetentionFull))
+	}
+	if err := logging.Validate(config.LogFormat, config.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("config: %w", err))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	config.InputPath = filepath.Join(config.DataRoot, "ingest", "input")
+	config.FinishedPath = filepath.Join(config.DataRoot, "ingest", "finished")
+	config.ThumbsPath = filepath.Join(config.DataRoot, "images", "thumbs")
+
+	quality, err := ai.LoadQualityPolicy(config.QualityPolicyPath)
+	if err != nil {
+		return nil, err
+	}
+	config.Quality = quality
+
+	return config, nil
+}
+
+// PrintConfig writes the settings of config as YAML, which can be used as a
+// config file. Secrets are redacted.
+func PrintConfig(w io.Writer, config *Config) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for i := range settings {
+		s := &settings[i]
+		value := s.get(config)
+		if s.redact != nil && value != "" {
+			value = s.redact(value)
+		}
+		doc.Content = append(doc.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: s.key},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: value},
+		)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func lookupSetting(key string) *setting {
+	for i := range settings {
+		if settings[i].key == key {
+			return &settings[i]
+		}
+	}
+	return nil
+}
+
+// readConfigFile reads the top-level values of a YAML or TOML file, told
+// apart by the extension.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: read %s: %w", path, err)
+	}
+
+	var raw map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("config: %s: unknown format: use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: parse %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case nil:
+			values[key] = ""
+		case map[string]any:
+			return nil, fmt.Errorf("config: %s in %s: must be a single value or a list", key, path)
+		case []any:
+			// Lists are the same as comma separated values
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+	return values, nil
+}
+
+func stringSetting(key string, env string, usage string, field func(*Config) *string) setting {
+	return setting{
+		key:   key,
+		env:   env,
+		usage: usage,
+		set: func(c *Config, value string) error {
+			*field(c) = value
+			return nil
+		},
+		get: func(c *Config) string { return *field(c) },
+	}
+}
+
+func durationSetting(key string, env string, usage string, min time.Duration, field func(*Config) *time.Duration) setting {
+	return setting{
+		key:   key,
+		env:   env,
+		usage: usage,
+		set: func(c *Config, value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid duration %q: use e.g. 30s or 5m", value)
+			}
+			if d < min {
+				return fmt.Errorf("%s is too short: must be at least %s", d, min)
+			}
+			*field(c) = d
+			return nil
+		},
+		get: func(c *Config) string { return field(c).String() },
+	}
+}
+
+// intSetting is a count, which must be at least 1.
+func intSetting(key string, env string, usage string, field func(*Config) *int) setting {
+	return setting{
+		key:   key,
+		env:   env,
+		usage: usage,
+		set: func(c *Config, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number %q: must be at least 1", value)
+			}
+			*field(c) = n
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Itoa(*field(c)) },
+	}
+}
+
+func boolSetting(key string, env string, usage string, field func(*Config) *bool) setting {
+	return setting{
+		key:   key,
+		env:   env,
+		usage: usage,
+		set: func(c *Config, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value %q: use true or false", value)
+			}
+			*field(c) = enabled
+			return nil
+		},
+		get:     func(c *Config) string { return strconv.FormatBool(*field(c)) },
+		boolean: true,
+	}
+}
+
+func withRedact(s setting, redact func(string) string) setting {
+	s.redact = redact
+	return s
+}
+
+func redactAll(string) string {
+	return "xxxxx"
+}
+
+var passwordParam = regexp.MustCompile(`(?i)(password\s*=\s*)('[^']*'|\S+)`)
+
+// redactDatabaseUrl hides the password of a URL or key=value connection
+// string.
+func redactDatabaseUrl(value string) string {
+	if u, err := url.Parse(value); err == nil && u.Scheme != "" {
+		if query := u.Query(); query.Has("password") {
+			query.Set("password", "xxxxx")
+			u.RawQuery = query.Encode()
+		}
+		return u.Redacted()
+	}
+	return passwordParam.ReplaceAllString(value, "${1}xxxxx")
+}
+
+var sizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// parseSize parses a byte count, optionally with a KB, MB or GB suffix. The
+// units are powers of 1024.
+func parseSize(value string) (int64, error) {
+	number := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if trimmed, ok := strings.CutSuffix(number, unit.suffix); ok {
+			number = strings.TrimSpace(trimmed)
+			multiplier = unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 1 || n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("invalid size %q: use e.g. 32MB", value)
+	}
+	return n * multiplier, nil
+}
+
+func formatSize(size int64) string {
+	for _, unit := range sizeUnits {
+		if size%unit.size == 0 && size >= unit.size {
+			return strconv.FormatInt(size/unit.size, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(size, 10)
+}
@@ -0,0 +1,272 @@
+package app
+
+import (
+	"bytes"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// loadConfig runs LoadConfig in a clean environment holding only env, with
+// file as the config file when it isn't empty and args as the flags.
+func loadConfig(t *testing.T, file string, env map[string]string, args []string, required ...string) (*Config, error) {
+	t.Helper()
+	for _, name := range append(settingEnvs(), ConfigFileEnv) {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+	if file != "" {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv(ConfigFileEnv, path)
+	}
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return LoadConfig(fs, required...)
+}
+
+func settingEnvs() []string {
+	envs := make([]string, 0, len(settings))
+	for _, s := range settings {
+		envs = append(envs, s.env)
+	}
+	return envs
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{"default", "", nil, nil, "localhost:8080"},
+		{"file", "web_endpoint: file:1", nil, nil, "file:1"},
+		{"env over file", "web_endpoint: file:1", map[string]string{"WEB_ENDPOINT": "env:1"}, nil, "env:1"},
+		{"flag over env", "web_endpoint: file:1", map[string]string{"WEB_ENDPOINT": "env:1"}, []string{"--web-endpoint=flag:1"}, "flag:1"},
+		{"empty env", "web_endpoint: file:1", map[string]string{"WEB_ENDPOINT": ""}, nil, ""},
+		{"empty file value", "web_endpoint:", nil, nil, ""},
+		{"empty flag", "", map[string]string{"WEB_ENDPOINT": "env:1"}, []string{"--web-endpoint="}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadConfig(t, tt.file, tt.env, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.WebEndpoint != tt.want {
+				t.Errorf("WebEndpoint = %q, want %q", config.WebEndpoint, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigGrpcOptIn(t *testing.T) {
+	config, err := loadConfig(t, "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.GrpcEndpoint != "" {
+		t.Errorf("GrpcEndpoint = %q by default, want the gRPC API off", config.GrpcEndpoint)
+	}
+
+	config, err = loadConfig(t, "grpc_endpoint: localhost:9090", map[string]string{"GRPC_ENDPOINT": ""}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.GrpcEndpoint != "" {
+		t.Errorf("GrpcEndpoint = %q, want an empty GRPC_ENDPOINT to turn it off", config.GrpcEndpoint)
+	}
+}
+
+func TestLoadConfigValues(t *testing.T) {
+	file := `
+data_root: /srv/face-match
+read_timeout: 1m30s
+max_upload_size: 8 MB
+cors_origins: [https://a.example, "https://b.example:8443"]
+webhook_allowed_networks: 10.1.2.3/16, 192.168.0.7
+privacy_mode: true
+`
+	config, err := loadConfig(t, file,
+		map[string]string{"QUERY_RETENTION_TTL": "2h", "JOB_WORKERS": "5"},
+		[]string{"--trace-sample-ratio=0.25", "--privacy-mode"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.ReadTimeout != 90*time.Second {
+		t.Errorf("ReadTimeout = %s, want 1m30s", config.ReadTimeout)
+	}
+	if config.QueryRetentionTTL != 2*time.Hour {
+		t.Errorf("QueryRetentionTTL = %s, want 2h", config.QueryRetentionTTL)
+	}
+	if config.MaxUploadSize != 8<<20 {
+		t.Errorf("MaxUploadSize = %d, want 8MB", config.MaxUploadSize)
+	}
+	if want := []string{"https://a.example", "https://b.example:8443"}; !slices.Equal(config.CorsOrigins, want) {
+		t.Errorf("CorsOrigins = %v, want %v", config.CorsOrigins, want)
+	}
+	want := []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16"), netip.MustParsePrefix("192.168.0.7/32")}
+	if !slices.Equal(config.WebhookAllowedNetworks, want) {
+		t.Errorf("WebhookAllowedNetworks = %v, want %v", config.WebhookAllowedNetworks, want)
+	}
+	if config.JobWorkers != 5 || config.TraceSampleRatio != 0.25 || !config.PrivacyMode {
+		t.Errorf("JobWorkers %d, TraceSampleRatio %g, PrivacyMode %t, want 5, 0.25, true", config.JobWorkers, config.TraceSampleRatio, config.PrivacyMode)
+	}
+	if want := filepath.Join("/srv/face-match", "ingest", "input"); config.InputPath != want {
+		t.Errorf("InputPath = %q, want %q", config.InputPath, want)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		env      map[string]string
+		required []string
+		want     string
+	}{
+		{"trace ratio", "trace_sample_ratio: 1.5", nil, nil, "trace_sample_ratio (from "},
+		{"duration", "", map[string]string{"READ_TIMEOUT": "30"}, nil, "read_timeout (from READ_TIMEOUT): invalid duration"},
+		{"empty duration", "", map[string]string{"WRITE_TIMEOUT": ""}, nil, "write_timeout (from WRITE_TIMEOUT): invalid duration"},
+		{"short duration", "canary_interval: 10ms", nil, nil, "canary_interval (from "},
+		{"negative duration", "idle_timeout: -1s", nil, nil, "must be at least 0s"},
+		{"origin", "", map[string]string{"CORS_ORIGINS": "example.com"}, nil, `invalid origin "example.com"`},
+		{"origin path", "cors_origins: https://example.com/app", nil, nil, "invalid origin"},
+		{"network", "webhook_allowed_networks: 10.1.0.0/33", nil, nil, "invalid network"},
+		{"retention", "query_retention: forever", nil, nil, `unknown policy "forever"`},
+		{"size", "max_upload_size: 0", nil, nil, "invalid size"},
+		{"count", "", map[string]string{"JOB_WORKERS": "0"}, nil, "job_workers (from JOB_WORKERS): invalid number"},
+		{"bool", "", map[string]string{"PRIVACY_MODE": "maybe"}, nil, "privacy_mode (from PRIVACY_MODE): invalid value"},
+		{"unknown key", "web_endpont: x", nil, nil, `unknown setting "web_endpont"`},
+		{"required", "", nil, []string{"database_url"}, "database_url is required"},
+		{"empty required", "", map[string]string{"DATABASE_URL": ""}, []string{"database_url"}, "database_url is required"},
+		{"privacy", "privacy_mode: true\nquery_retention: full", nil, nil, "privacy_mode forbids"},
+		{"log level", "log_level: info,ai=loud", nil, nil, "invalid level"},
+		{"log format", "log_format: xml", nil, nil, "unknown format"},
+		{"nested", "quality:\n  min: 1", nil, nil, "must be a single value or a list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadConfig(t, tt.file, tt.env, nil, tt.required...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigReportsEveryError(t *testing.T) {
+	_, err := loadConfig(t, "max_upload_size: lots", map[string]string{"JOB_WORKERS": "none"}, []string{"--read-timeout=soon"})
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	for _, want := range []string{"max_upload_size (from ", "job_workers (from JOB_WORKERS)", "read_timeout (from --read-timeout)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%v doesn't report %q", err, want)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"1024", 1024},
+		{"12B", 12},
+		{"64KB", 64 << 10},
+		{" 32 mb ", 32 << 20},
+		{"2GB", 2 << 30},
+		{"", 0},
+		{"-1MB", 0},
+		{"1.5MB", 0},
+		{"1TB", 0},
+		{"9223372036854775807GB", 0},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.value)
+		if tt.want == 0 {
+			if err == nil {
+				t.Errorf("parseSize(%q) = %d, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+		if back, err := parseSize(formatSize(got)); err != nil || back != got {
+			t.Errorf("formatSize(%d) = %q, which parses as %d, %v", got, formatSize(got), back, err)
+		}
+	}
+}
+
+func TestPrintConfigRedactsSecrets(t *testing.T) {
+	tests := []struct {
+		databaseUrl string
+		want        string
+	}{
+		{"postgres://face:hunter2@db:5432/face", "postgres://face:xxxxx@db:5432/face"},
+		{"postgres://face@db/face?password=hunter2&sslmode=require", "password=xxxxx"},
+		{"host=db user=face password=hunter2 dbname=face", "password=xxxxx dbname=face"},
+		{"host=db password = 'hunter2 with spaces'", "password = xxxxx"},
+	}
+	for _, tt := range tests {
+		config := Default()
+		config.DatabaseUrl = tt.databaseUrl
+		config.AdminToken = "hunter2-admin"
+
+		var out bytes.Buffer
+		if err := PrintConfig(&out, config); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out.String(), "hunter2") {
+			t.Errorf("secret printed for %q:\n%s", tt.databaseUrl, out.String())
+		}
+		if !strings.Contains(out.String(), tt.want) {
+			t.Errorf("printed config for %q doesn't contain %q:\n%s", tt.databaseUrl, tt.want, out.String())
+		}
+		if !strings.Contains(out.String(), "admin_token: xxxxx") {
+			t.Errorf("admin token not redacted:\n%s", out.String())
+		}
+	}
+}
+
+func TestPrintConfigLoadsBack(t *testing.T) {
+	config := Default()
+	config.CorsOrigins = []string{"https://a.example", "https://b.example"}
+	config.MaxUploadSize = 48 << 20
+	config.CanaryInterval = 90 * time.Minute
+
+	var out bytes.Buffer
+	if err := PrintConfig(&out, config); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadConfig(t, out.String(), nil, nil)
+	if err != nil {
+		t.Fatalf("printed config doesn't load: %v\n%s", err, out.String())
+	}
+	for _, s := range settings {
+		if got, want := s.get(loaded), s.get(config); got != want {
+			t.Errorf("%s = %q after loading, want %q", s.key, got, want)
+		}
+	}
+}
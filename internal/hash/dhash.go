@@ -1,36 +1,32 @@
 package hash
 
 import (
-	"bytes"
 	"fmt"
 	"image"
-	"image/color"
-	_ "image/jpeg"
-	_ "image/png"
 
+	"github.com/face-match/internal/imaging"
 	"golang.org/x/image/draw"
-	_ "golang.org/x/image/webp"
 )
 
+// DHash64 hashes the upright first frame of the image.
 func DHash64(imgBytes []byte) (int64, error) {
-	img, _, err := image.Decode(bytes.NewReader(imgBytes))
+	decoded, err := imaging.Decode(imgBytes)
 	if err != nil {
 		return 0, fmt.Errorf("dhash: decode: %w", err)
 	}
-	return DHash64FromImage(img), nil
+	return DHash64FromImage(decoded.First()), nil
 }
 
 func DHash64FromImage(img image.Image) int64 {
 	dst := image.NewRGBA(image.Rect(0, 0, 9, 8))
 	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Over, nil)
 
+	gray := imaging.Luma(dst, dst.Bounds())
+
 	var h uint64
 	var bit uint = 0
 	for y := 0; y < 8; y++ {
-		var row [9]uint8
-		for x := 0; x < 9; x++ {
-			row[x] = luma8(dst.At(x, y))
-		}
+		row := gray[y*9 : (y+1)*9]
 		for x := 0; x < 8; x++ {
 			if row[x] > row[x+1] {
 				h |= 1 << bit
@@ -41,12 +37,3 @@ func DHash64FromImage(img image.Image) int64 {
 
 	return int64(h)
 }
-
-func luma8(c color.Color) uint8 {
-	r, g, b, _ := c.RGBA()
-	R := uint32(r >> 8)
-	G := uint32(g >> 8)
-	B := uint32(b >> 8)
-	Y := (299*R + 587*G + 114*B + 500) / 1000
-	return uint8(Y)
-}
@@ -0,0 +1,144 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// MaxGifPixels bounds the pixels of all the frames of a GIF together.
+// gif.DecodeAll allocates every frame, however few are kept, and a frame of
+// a single colour compresses to almost nothing.
+const MaxGifPixels = 4 * MaxPixels
+
+var errGifFormat = errors.New("imaging: malformed gif")
+
+// GifDelay converts a GIF frame delay to a duration. Like browsers, very
+// short delays are treated as 100ms.
+func GifDelay(hundredths int) time.Duration {
+	if hundredths < 2 {
+		hundredths = 10
+	}
+	return time.Duration(hundredths) * 10 * time.Millisecond
+}
+
+// CompositeGif renders each frame of an animated GIF onto a full canvas,
+// honouring the disposal methods, and passes it to visit. The canvas is
+// reused between calls, so visit must copy it to keep it. Returning false
+// stops the iteration.
+func CompositeGif(g *gif.GIF, visit func(i int, canvas *image.RGBA) bool) error {
+	if len(g.Image) == 0 {
+		return fmt.Errorf("imaging: gif has no frames")
+	}
+
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		bounds = g.Image[0].Bounds()
+	}
+	canvas := image.NewRGBA(bounds)
+	var previous *image.RGBA
+
+	for i, frame := range g.Image {
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = CloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		if !visit(i, canvas) {
+			return nil
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return nil
+}
+
+// CloneRGBA copies an image, e.g. a canvas passed to a CompositeGif visitor.
+func CloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	copy(dst.Pix, src.Pix)
+	return dst
+}
+
+// CheckGif refuses with ErrTooLarge a GIF whose logical screen fails
+// CheckSize or whose frames together hold more than MaxGifPixels. It reads
+// only the block structure, without decompressing anything, so it is called
+// before gif.DecodeAll.
+func CheckGif(data []byte) error {
+	if len(data) < 13 || string(data[:3]) != "GIF" {
+		return errGifFormat
+	}
+	width := int(binary.LittleEndian.Uint16(data[6:]))
+	height := int(binary.LittleEndian.Uint16(data[8:]))
+	if err := CheckSize(width, height); err != nil {
+		return err
+	}
+
+	pos := 13 + colorTableSize(data[10])
+	var frames int
+	var pixels int64
+	for {
+		if pos >= len(data) {
+			return fmt.Errorf("%w: %w", errGifFormat, io.ErrUnexpectedEOF)
+		}
+		block := data[pos]
+		pos++
+		switch block {
+		case 0x21: // Extension: a label, then sub-blocks
+			pos++
+		case 0x2c: // Image descriptor, then the LZW minimum code size and sub-blocks
+			if pos+9 > len(data) {
+				return fmt.Errorf("%w: %w", errGifFormat, io.ErrUnexpectedEOF)
+			}
+			frames++
+			pixels += int64(binary.LittleEndian.Uint16(data[pos+4:])) * int64(binary.LittleEndian.Uint16(data[pos+6:]))
+			if pixels > MaxGifPixels {
+				return fmt.Errorf("%w: %d frames hold more than %d pixels", ErrTooLarge, frames, int64(MaxGifPixels))
+			}
+			pos += 9 + colorTableSize(data[pos+8]) + 1
+		case 0x3b: // Trailer
+			return nil
+		default:
+			return fmt.Errorf("%w: unknown block 0x%02x", errGifFormat, block)
+		}
+		pos = skipSubBlocks(data, pos)
+	}
+}
+
+// colorTableSize is the length of the colour table a logical screen or
+// image descriptor with the given packed field is followed by.
+func colorTableSize(packed byte) int {
+	if packed&0x80 == 0 {
+		return 0
+	}
+	return 3 << (packed&0x07 + 1)
+}
+
+// skipSubBlocks returns the position after the data sub-blocks at pos, each
+// a length byte followed by that many bytes, ended by an empty one. Past the
+// end of data means it was truncated.
+func skipSubBlocks(data []byte, pos int) int {
+	for pos < len(data) {
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos
+		}
+		pos += size
+	}
+	return len(data) + 1
+}
@@ -0,0 +1,91 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"testing"
+)
+
+// gifBomb repeats a blank size x size frame, which compresses to a few
+// kilobytes however large it is.
+func gifBomb(t *testing.T, size, frames int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, size, size), palette.Plan9), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	header := 13 + colorTableSize(data[10])
+	frame := data[header : len(data)-1]
+
+	bomb := bytes.Clone(data[:header])
+	for range frames {
+		bomb = append(bomb, frame...)
+	}
+	return append(bomb, 0x3b)
+}
+
+func animatedGif(t *testing.T, frames int) []byte {
+	t.Helper()
+	g := &gif.GIF{}
+	for i := range frames {
+		frame := image.NewPaletted(image.Rect(0, 0, 40, 30), palette.Plan9)
+		frame.Set(i, i, color.White)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCheckGif(t *testing.T) {
+	valid := animatedGif(t, 3)
+	if err := CheckGif(valid); err != nil {
+		t.Errorf("CheckGif(3 frames) = %v", err)
+	}
+	bomb := gifBomb(t, 7000, 1000)
+	if err := CheckGif(gifBomb(t, 7000, 4)); err != nil {
+		t.Errorf("CheckGif(4 frames of 7000x7000) = %v, want them within MaxGifPixels", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"many frames", bomb, ErrTooLarge},
+		{"huge screen", []byte("GIF89a\x20\x4e\x20\x4e\x00\x00\x00\x3b"), ErrTooLarge},
+		{"truncated", valid[:len(valid)-20], errGifFormat},
+		{"no trailer", valid[:len(valid)-1], errGifFormat},
+		{"unknown block", append(bytes.Clone(valid[:len(valid)-1]), 0x99), errGifFormat},
+		{"not a gif", []byte("PNG"), errGifFormat},
+	}
+	for _, test := range tests {
+		if err := CheckGif(test.data); !errors.Is(err, test.want) {
+			t.Errorf("%s: CheckGif() = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestDecodeRefusesGifBomb(t *testing.T) {
+	// 1000 frames of 49 megapixels, each under MaxPixels, in 37MB
+	bomb := gifBomb(t, 7000, 1000)
+	if _, err := Decode(bomb); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Decode() = %v, want ErrTooLarge", err)
+	}
+
+	decoded, err := Decode(animatedGif(t, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Frames) != 3 {
+		t.Errorf("decoded %d frames, want 3", len(decoded.Frames))
+	}
+}
@@ -0,0 +1,141 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"path/filepath"
+	"strings"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// The most frames taken from an animated image, each of which is searched
+// on its own. Fewer are taken when together they would hold more than
+// MaxPixels, so an animation never costs more memory than the largest still
+// image allowed. The frames taken are spread evenly over the animation.
+const MaxSampledFrames = 64
+
+// Formats the sidecar decodes reliably. Anything else is re-encoded before
+// it is uploaded.
+var sidecarFormats = map[string]bool{
+	"jpeg": true,
+	"png":  true,
+	"webp": true,
+}
+
+var extensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".webp": true,
+	".gif":  true,
+	".bmp":  true,
+	".tif":  true,
+	".tiff": true,
+}
+
+// IsSupportedFile reports whether the file extension is one of the image
+// formats handled by ingest and search.
+func IsSupportedFile(filename string) bool {
+	return extensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+type Decoded struct {
+	Format      string
+	Orientation int           // EXIF orientation of the source, 1 when absent
+	Frames      []image.Image // Upright; more than one for animations
+}
+
+// Decode decodes an image, turning it upright according to its EXIF
+// orientation. Animated GIFs yield every frame, up to the cap described at
+// MaxSampledFrames. Images that fail Check aren't decoded.
+func Decode(data []byte) (*Decoded, error) {
+	_, format, err := Check(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "gif" {
+		frames, err := decodeGifFrames(data)
+		if err != nil {
+			return nil, err
+		}
+		return &Decoded{Format: format, Orientation: 1, Frames: frames}, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("imaging: decode: %w", err)
+	}
+
+	orientation := readOrientation(format, data)
+	return &Decoded{
+		Format:      format,
+		Orientation: orientation,
+		Frames:      []image.Image{Orient(img, orientation)},
+	}, nil
+}
+
+// First returns the first frame.
+func (d *Decoded) First() image.Image {
+	return d.Frames[0]
+}
+
+func EncodeJpeg(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		return nil, fmt.Errorf("imaging: encode jpeg: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeGifFrames(data []byte) ([]image.Image, error) {
+	if err := CheckGif(data); err != nil {
+		return nil, err
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("imaging: decode gif: %w", err)
+	}
+
+	wanted := sampleIndexes(len(g.Image), gifFrameBudget(g.Config.Width, g.Config.Height))
+	frames := make([]image.Image, 0, len(wanted))
+	err = CompositeGif(g, func(i int, canvas *image.RGBA) bool {
+		if wanted[i] {
+			frames = append(frames, CloneRGBA(canvas))
+		}
+		return len(frames) < len(wanted)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
+
+// gifFrameBudget is how many frames of a width x height animation may be
+// kept: MaxSampledFrames, or fewer when they would exceed MaxPixels.
+func gifFrameBudget(width, height int) int {
+	pixels := max(1, int64(width)*int64(height))
+	return int(max(1, min(MaxSampledFrames, MaxPixels/pixels)))
+}
+
+// sampleIndexes picks up to max indexes spread evenly over count items.
+func sampleIndexes(count, max int) map[int]bool {
+	out := make(map[int]bool, max)
+	if count <= max {
+		for i := 0; i < count; i++ {
+			out[i] = true
+		}
+		return out
+	}
+	for i := 0; i < max; i++ {
+		out[i*count/max] = true
+	}
+	return out
+}
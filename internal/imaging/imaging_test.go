@@ -0,0 +1,32 @@
+package imaging
+
+import "testing"
+
+func TestGifFrameBudget(t *testing.T) {
+	tests := []struct {
+		width, height int
+		want          int
+	}{
+		{320, 240, MaxSampledFrames},
+		{1250, 1000, 40},
+		{10000, 5000, 1},
+		{0, 0, MaxSampledFrames},
+	}
+	for _, test := range tests {
+		if got := gifFrameBudget(test.width, test.height); got != test.want {
+			t.Errorf("gifFrameBudget(%d, %d) = %d, want %d", test.width, test.height, got, test.want)
+		}
+	}
+}
+
+func TestSampleIndexes(t *testing.T) {
+	if got := sampleIndexes(5, MaxSampledFrames); len(got) != 5 {
+		t.Errorf("sampleIndexes(5) kept %d frames, want all 5", len(got))
+	}
+	got := sampleIndexes(100, 4)
+	for _, i := range []int{0, 25, 50, 75} {
+		if !got[i] {
+			t.Errorf("sampleIndexes(100, 4) = %v, want frame %d", got, i)
+		}
+	}
+}
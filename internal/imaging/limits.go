@@ -0,0 +1,99 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"os"
+)
+
+// Limits on the images that are decoded, checked against the header before
+// any pixels are allocated.
+const (
+	MaxDimension = 16384
+	MaxPixels    = 50_000_000
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("imaging: unsupported format")
+	ErrTooLarge          = errors.New("imaging: image too large")
+	ErrFileTooLarge      = errors.New("imaging: file too large")
+)
+
+// Media types that are decoded. Anything else is refused before a decoder
+// sees it.
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/bmp":  true,
+	"image/tiff": true,
+}
+
+// SniffType returns the media type of data judging by its content alone.
+func SniffType(data []byte) string {
+	// Not among the types the standard sniffer knows
+	if bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")) {
+		return "image/tiff"
+	}
+	return http.DetectContentType(data)
+}
+
+// Check verifies that data is an image of an allowed type whose dimensions
+// are within the limits, reading only its header.
+func Check(data []byte) (image.Config, string, error) {
+	if mediaType := SniffType(data); !allowedTypes[mediaType] {
+		return image.Config{}, "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, mediaType)
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return image.Config{}, "", fmt.Errorf("imaging: decode: %w", err)
+	}
+	if err := CheckSize(config.Width, config.Height); err != nil {
+		return image.Config{}, "", err
+	}
+	return config, format, nil
+}
+
+// CheckSize refuses dimensions over MaxDimension or MaxPixels with
+// ErrTooLarge. Decoders of other containers, e.g. video frames, call it
+// before allocating the image.
+func CheckSize(width, height int) error {
+	if width > MaxDimension || height > MaxDimension || int64(width)*int64(height) > MaxPixels {
+		return fmt.Errorf("%w: %dx%d", ErrTooLarge, width, height)
+	}
+	return nil
+}
+
+// ReadFile reads an image file, refusing one over maxSize bytes without
+// reading it.
+func ReadFile(path string, maxSize int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxSize {
+		return nil, fmt.Errorf("%w: %s is %d bytes", ErrFileTooLarge, path, info.Size())
+	}
+
+	// The file may have grown since
+	data, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: %s", ErrFileTooLarge, path)
+	}
+	return data, nil
+}
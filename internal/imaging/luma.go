@@ -0,0 +1,85 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+)
+
+// Luma returns the luma of every pixel in r, row by row. r must lie within
+// the image's bounds.
+//
+// The common decoded types are read straight from their pixel buffers,
+// without going through color.Color, which gives exactly ColorLuma for all
+// but YCbCr images. Their luma is the Y plane itself, which JPEG encodes
+// with the same BT.601 weights: it is within 1 of ColorLuma, except where
+// subsampled chroma makes a colour out of gamut and converting it to RGB
+// would clamp it.
+func Luma(img image.Image, r image.Rectangle) []uint8 {
+	w, h := r.Dx(), r.Dy()
+	out := make([]uint8, w*h)
+
+	switch src := img.(type) {
+	case *image.YCbCr:
+		for y := 0; y < h; y++ {
+			i := src.YOffset(r.Min.X, r.Min.Y+y)
+			copy(out[y*w:(y+1)*w], src.Y[i:i+w])
+		}
+	case *image.Gray:
+		for y := 0; y < h; y++ {
+			i := src.PixOffset(r.Min.X, r.Min.Y+y)
+			copy(out[y*w:(y+1)*w], src.Pix[i:i+w])
+		}
+	case *image.RGBA:
+		for y := 0; y < h; y++ {
+			pix := src.Pix[src.PixOffset(r.Min.X, r.Min.Y+y):]
+			row := out[y*w : (y+1)*w]
+			for x := range row {
+				p := pix[x*4 : x*4+3 : x*4+3]
+				row[x] = luma(uint32(p[0]), uint32(p[1]), uint32(p[2]))
+			}
+		}
+	case *image.NRGBA:
+		for y := 0; y < h; y++ {
+			pix := src.Pix[src.PixOffset(r.Min.X, r.Min.Y+y):]
+			row := out[y*w : (y+1)*w]
+			for x := range row {
+				p := pix[x*4 : x*4+4 : x*4+4]
+				a := uint32(p[3])
+				if a == 0xff {
+					row[x] = luma(uint32(p[0]), uint32(p[1]), uint32(p[2]))
+					continue
+				}
+				// Premultiply as color.NRGBA.RGBA does
+				a |= a << 8
+				row[x] = luma(premultiply(p[0], a), premultiply(p[1], a), premultiply(p[2], a))
+			}
+		}
+	default:
+		i := 0
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				out[i] = ColorLuma(img.At(x, y))
+				i++
+			}
+		}
+	}
+	return out
+}
+
+// ColorLuma is the BT.601 luma of the colour.
+func ColorLuma(c color.Color) uint8 {
+	r, g, b, _ := c.RGBA()
+	return luma(r>>8, g>>8, b>>8)
+}
+
+func luma(r, g, b uint32) uint8 {
+	return uint8((299*r + 587*g + 114*b + 500) / 1000)
+}
+
+// premultiply returns the 8-bit premultiplied value of an 8-bit channel and a
+// 16-bit alpha.
+func premultiply(v uint8, a uint32) uint32 {
+	c := uint32(v)
+	c |= c << 8
+	return (c * a / 0xffff) >> 8
+}
@@ -0,0 +1,147 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math/rand/v2"
+	"testing"
+)
+
+// atLuma is Luma through At and ColorLuma, which Luma must match exactly.
+func atLuma(img image.Image, r image.Rectangle) []uint8 {
+	out := make([]uint8, 0, r.Dx()*r.Dy())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			out = append(out, ColorLuma(img.At(x, y)))
+		}
+	}
+	return out
+}
+
+func randomYCbCr(rng *rand.Rand, b image.Rectangle, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	img := image.NewYCbCr(b, ratio)
+	for _, plane := range [][]uint8{img.Y, img.Cb, img.Cr} {
+		for i := range plane {
+			// The full range, so out-of-gamut colours are clamped
+			plane[i] = uint8(rng.UintN(256))
+		}
+	}
+	return img
+}
+
+func randomPix(rng *rand.Rand, pix []uint8) {
+	for i := range pix {
+		pix[i] = uint8(rng.UintN(256))
+	}
+}
+
+// lumaImages are the types Luma reads directly, and one it doesn't. Their
+// bounds don't start at the origin. The YCbCr ones have random planes, so
+// many of their colours are out of gamut; Luma reads their Y plane.
+func lumaImages() map[string]image.Image {
+	rng := rand.New(rand.NewPCG(1, 2))
+	b := image.Rect(3, 5, 3+97, 5+61)
+
+	gray := image.NewGray(b)
+	randomPix(rng, gray.Pix)
+	rgba := image.NewRGBA(b)
+	randomPix(rng, rgba.Pix)
+	nrgba := image.NewNRGBA(b)
+	randomPix(rng, nrgba.Pix)
+	for i := 3; i < len(nrgba.Pix); i += 8 {
+		nrgba.Pix[i] = 0xff // Half opaque, half not
+	}
+	rgba64 := image.NewRGBA64(b)
+	randomPix(rng, rgba64.Pix)
+
+	return map[string]image.Image{
+		"YCbCr444": randomYCbCr(rng, b, image.YCbCrSubsampleRatio444),
+		"YCbCr422": randomYCbCr(rng, b, image.YCbCrSubsampleRatio422),
+		"YCbCr420": randomYCbCr(rng, b, image.YCbCrSubsampleRatio420),
+		"Gray":     gray,
+		"RGBA":     rgba,
+		"NRGBA":    nrgba,
+		"RGBA64":   rgba64,
+	}
+}
+
+func TestLumaMatchesColorLuma(t *testing.T) {
+	rects := []image.Rectangle{
+		image.Rect(3, 5, 100, 66),  // Everything
+		image.Rect(4, 6, 17, 19),   // Odd corner, so chroma starts mid-block
+		image.Rect(50, 30, 99, 65), // Odd size, up to the far edge
+		image.Rect(7, 9, 8, 10),    // One pixel
+	}
+	for name, img := range lumaImages() {
+		for _, r := range rects {
+			got := Luma(img, r)
+			want := atLuma(img, r)
+			if ycbcr, ok := img.(*image.YCbCr); ok {
+				want = want[:0]
+				for y := r.Min.Y; y < r.Max.Y; y++ {
+					for x := r.Min.X; x < r.Max.X; x++ {
+						want = append(want, ycbcr.Y[ycbcr.YOffset(x, y)])
+					}
+				}
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					x, y := r.Min.X+i%r.Dx(), r.Min.Y+i/r.Dx()
+					t.Errorf("%s %v: Luma at (%d, %d) = %d, want %d", name, r, x, y, got[i], want[i])
+					break
+				}
+			}
+		}
+	}
+}
+
+// TestYCbCrLuma checks that the Y plane, which Luma reads, is within 1 of
+// ColorLuma for colours that came from RGB, as in a JPEG without chroma
+// subsampling.
+func TestYCbCrLuma(t *testing.T) {
+	img := image.NewYCbCr(image.Rect(0, 0, 64, 64*64), image.YCbCrSubsampleRatio444)
+	i := 0
+	for r := 0; r < 256; r += 4 {
+		for g := 0; g < 256; g += 4 {
+			for b := 0; b < 256; b += 4 {
+				img.Y[i], img.Cb[i], img.Cr[i] = color.RGBToYCbCr(uint8(r), uint8(g), uint8(b))
+				i++
+			}
+		}
+	}
+
+	got := Luma(img, img.Bounds())
+	want := atLuma(img, img.Bounds())
+	for i := range want {
+		if diff := int(got[i]) - int(want[i]); diff < -1 || diff > 1 {
+			t.Fatalf("Luma of YCbCr(%d, %d, %d) = %d, ColorLuma = %d", img.Y[i], img.Cb[i], img.Cr[i], got[i], want[i])
+		}
+	}
+}
+
+func TestColorLumaOfGray(t *testing.T) {
+	for v := 0; v < 256; v++ {
+		if got := ColorLuma(color.Gray{Y: uint8(v)}); got != uint8(v) {
+			t.Fatalf("ColorLuma(Gray %d) = %d", v, got)
+		}
+	}
+}
+
+func benchmarkLuma(b *testing.B, luma func(image.Image, image.Rectangle) []uint8) {
+	for name, img := range lumaImages() {
+		b.Run(name, func(b *testing.B) {
+			r := img.Bounds()
+			for b.Loop() {
+				luma(img, r)
+			}
+		})
+	}
+}
+
+func BenchmarkLuma(b *testing.B) {
+	benchmarkLuma(b, Luma)
+}
+
+func BenchmarkColorLuma(b *testing.B) {
+	benchmarkLuma(b, atLuma)
+}
@@ -0,0 +1,125 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// StripMetadata returns the image without its EXIF (which includes GPS
+// positions), XMP, IPTC and comments, leaving the pixels untouched. Colour
+// profiles are kept. For formats it doesn't know, and data it can't parse,
+// it returns false; the metadata may still be there, so such images must be
+// re-encoded instead.
+func StripMetadata(format string, data []byte) ([]byte, bool) {
+	var stripped []byte
+	switch format {
+	case "jpeg":
+		stripped = stripJpeg(data)
+	case "png":
+		stripped = stripPng(data)
+	case "webp":
+		stripped = stripWebp(data)
+	}
+	return stripped, stripped != nil
+}
+
+// stripJpeg drops the APP1 (EXIF, XMP), APP13 (IPTC) and COM segments.
+func stripJpeg(data []byte) []byte {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return nil
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+
+	i := 2 // Skip SOI
+	for i+4 <= len(data) && data[i] == 0xFF {
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// Metadata always comes before the image data
+			return append(out, data[i:]...)
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if i+2+length > len(data) {
+			return nil
+		}
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out = append(out, data[i:i+2+length]...)
+		}
+		i += 2 + length
+	}
+	return nil
+}
+
+// pngMetadataChunks are the chunks stripPng drops.
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+func stripPng(data []byte) []byte {
+	if len(data) < 8 {
+		return nil
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:8]...)
+
+	i := 8 // Skip signature
+	for i+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		kind := string(data[i+4 : i+8])
+		if i+12+length > len(data) {
+			return nil
+		}
+		if !pngMetadataChunks[kind] {
+			out = append(out, data[i:i+12+length]...)
+		}
+		i += 12 + length
+		if kind == "IEND" {
+			return out
+		}
+	}
+	return nil
+}
+
+// VP8X flags announcing the chunks stripWebp drops.
+const (
+	webpExifFlag = 0x08
+	webpXmpFlag  = 0x04
+)
+
+func stripWebp(data []byte) []byte {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:12]...)
+
+	i := 12 // Skip "RIFF", size and "WEBP"
+	for i+8 <= len(data) {
+		kind := string(data[i : i+4])
+		length := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		end := i + 8 + length + length%2 // Chunks are padded to an even size
+		if i+8+length > len(data) {
+			return nil
+		}
+		end = min(end, len(data))
+
+		switch kind {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if length > 0 {
+				out[start+8] &^= webpExifFlag | webpXmpFlag
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out
+}
@@ -0,0 +1,56 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// withExif returns the JPEG with an APP1 segment inserted after SOI, whose
+// length is declared as length.
+func withExif(t *testing.T, length int) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	encoded, err := EncodeJpeg(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	segment := []byte{0xFF, 0xE1, byte(length >> 8), byte(length), 'E', 'x', 'i', 'f', 0, 0}
+	return append(append(append([]byte{}, encoded[:2]...), segment...), encoded[2:]...)
+}
+
+func TestStripMetadata(t *testing.T) {
+	stripped, ok := StripMetadata("jpeg", withExif(t, 8))
+	if !ok {
+		t.Fatal("StripMetadata failed on a well-formed JPEG")
+	}
+	if bytes.Contains(stripped, []byte("Exif")) {
+		t.Error("StripMetadata kept the EXIF segment")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped JPEG doesn't decode: %v", err)
+	}
+
+	if _, ok := StripMetadata("jpeg", withExif(t, 0xFFFF)); ok {
+		t.Error("StripMetadata succeeded on a segment running past the end")
+	}
+	if _, ok := StripMetadata("bmp", []byte("BM")); ok {
+		t.Error("StripMetadata succeeded on an unknown format")
+	}
+}
+
+func TestUploadsReencodesUnstrippable(t *testing.T) {
+	original := withExif(t, 0xFFFF)
+	d := &Decoded{Format: "jpeg", Frames: []image.Image{image.NewGray(image.Rect(0, 0, 16, 16))}, Orientation: 1}
+	uploads, err := d.Uploads(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(uploads[0].Bytes, []byte("Exif")) {
+		t.Error("upload kept the metadata it couldn't strip")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(uploads[0].Bytes)); err != nil {
+		t.Errorf("re-encoded upload doesn't decode: %v", err)
+	}
+}
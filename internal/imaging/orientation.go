@@ -0,0 +1,164 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+const exifOrientationTag = 0x0112
+
+// readOrientation returns the EXIF orientation (1-8) stored in the image, or
+// 1 when there is none. JPEG, PNG, WebP and TIFF can all carry EXIF.
+func readOrientation(format string, data []byte) int {
+	var exif []byte
+	switch format {
+	case "jpeg":
+		exif = jpegExif(data)
+	case "png":
+		exif = pngExif(data)
+	case "webp":
+		exif = webpExif(data)
+	case "tiff":
+		exif = data
+	}
+
+	orientation := tiffOrientation(exif)
+	if orientation < 1 || orientation > 8 {
+		return 1
+	}
+	return orientation
+}
+
+// jpegExif returns the TIFF structure from the APP1 Exif segment.
+func jpegExif(data []byte) []byte {
+	i := 2 // Skip SOI
+	for i+4 <= len(data) && data[i] == 0xFF {
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// Metadata always comes before the image data
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if i+2+length > len(data) {
+			return nil
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		i += 2 + length
+	}
+	return nil
+}
+
+func pngExif(data []byte) []byte {
+	i := 8 // Skip signature
+	for i+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		kind := string(data[i+4 : i+8])
+		if i+12+length > len(data) || kind == "IDAT" {
+			return nil
+		}
+		if kind == "eXIf" {
+			return data[i+8 : i+8+length]
+		}
+		i += 12 + length
+	}
+	return nil
+}
+
+func webpExif(data []byte) []byte {
+	i := 12 // Skip "RIFF", size and "WEBP"
+	for i+8 <= len(data) {
+		kind := string(data[i : i+4])
+		length := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		if i+8+length > len(data) {
+			return nil
+		}
+		if kind == "EXIF" {
+			return bytes.TrimPrefix(data[i+8:i+8+length], []byte("Exif\x00\x00"))
+		}
+		i += 8 + length + length%2 // Chunks are padded to an even size
+	}
+	return nil
+}
+
+// tiffOrientation reads the orientation tag from the first IFD.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[0:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:entry+2]) == exifOrientationTag {
+			return int(order.Uint16(tiff[entry+8 : entry+10]))
+		}
+	}
+	return 0
+}
+
+// Orient returns the image turned upright for the given EXIF orientation.
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		// Orientations 5-8 swap width and height
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored
+				dx, dy = w-1-x, y
+			case 3: // Rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // Flipped
+				dx, dy = x, h-1-y
+			case 5: // Transposed
+				dx, dy = y, x
+			case 6: // Rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // Transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // Rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			si := y*src.Stride + x*4
+			di := dy*dst.Stride + dx*4
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,81 @@
+package imaging
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// MaxUploadDimension caps the longest side of the working copy sent to the
+// sidecar. Its detector runs at 640x640, so larger images only cost
+// bandwidth and decoding time.
+const MaxUploadDimension = 1280
+
+// Upload is one frame prepared for the sidecar.
+type Upload struct {
+	Bytes []byte      // The encoded working copy
+	Image image.Image // The upright frame at full size
+	Scale float64     // Full size pixels per working copy pixel
+}
+
+// Uploads prepares a working copy of every frame. The original bytes, less
+// their metadata, are reused when the sidecar would see the same pixels;
+// otherwise, or when the metadata can't be stripped, the frame is scaled
+// down to MaxUploadDimension and re-encoded as JPEG, which carries no
+// metadata either.
+func (d *Decoded) Uploads(original []byte) ([]Upload, error) {
+	out := make([]Upload, 0, len(d.Frames))
+	for _, frame := range d.Frames {
+		scale := uploadScale(frame.Bounds())
+		if len(d.Frames) == 1 && d.Orientation == 1 && sidecarFormats[d.Format] && scale == 1 {
+			if stripped, ok := StripMetadata(d.Format, original); ok {
+				out = append(out, Upload{Bytes: stripped, Image: frame, Scale: 1})
+				continue
+			}
+		}
+
+		working := frame
+		if scale != 1 {
+			working = resize(frame, scale)
+		}
+		encoded, err := EncodeJpeg(working)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Upload{Bytes: encoded, Image: frame, Scale: scale})
+	}
+	return out, nil
+}
+
+// ToOriginal maps a box in working copy coordinates onto the full size frame.
+func (u *Upload) ToOriginal(bbox []float64) []float64 {
+	b := u.Image.Bounds()
+	out := make([]float64, len(bbox))
+	for i, v := range bbox {
+		if i%2 == 0 {
+			out[i] = v*u.Scale + float64(b.Min.X)
+		} else {
+			out[i] = v*u.Scale + float64(b.Min.Y)
+		}
+	}
+	return out
+}
+
+// uploadScale is how many times larger than the cap the image is, or 1 when
+// it fits.
+func uploadScale(b image.Rectangle) float64 {
+	longest := max(b.Dx(), b.Dy())
+	if longest <= MaxUploadDimension {
+		return 1
+	}
+	return float64(longest) / MaxUploadDimension
+}
+
+func resize(img image.Image, scale float64) image.Image {
+	b := img.Bounds()
+	w := max(1, int(float64(b.Dx())/scale+0.5))
+	h := max(1, int(float64(b.Dy())/scale+0.5))
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.BiLinear.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,168 @@
+// Package logging configures structured logging with log/slog. Each
+// subsystem logs through its own logger from For, whose level can be set
+// separately, e.g. LOG_LEVEL="info,ai=debug,http=warn".
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	mu           sync.RWMutex
+	root         slog.Handler = slog.NewTextHandler(os.Stderr, nil)
+	defaultLevel              = slog.LevelInfo
+	levels                    = map[string]slog.Level{}
+)
+
+// Setup replaces the output format ("text" or "json") and the levels. levels
+// is a default level optionally followed by subsystem=level pairs; either
+// may be left out. Loggers made by For before Setup follow the new settings.
+func Setup(w io.Writer, format string, levelSpec string) error {
+	def, perSubsystem, err := parseLevels(levelSpec)
+	if err != nil {
+		return err
+	}
+
+	// The subsystem handlers filter by level, so let everything through here
+	options := &slog.HandlerOptions{Level: slog.Level(-16)}
+	var handler slog.Handler
+	switch format {
+	case "", "text":
+		handler = slog.NewTextHandler(w, options)
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return fmt.Errorf("logging: unknown format %q: use text or json", format)
+	}
+
+	mu.Lock()
+	root = handler
+	defaultLevel = def
+	levels = perSubsystem
+	mu.Unlock()
+
+	// Also routes the standard log package through slog
+	slog.SetDefault(For("app"))
+	return nil
+}
+
+// Validate reports whether Setup would accept format and levelSpec, without
+// changing anything.
+func Validate(format string, levelSpec string) error {
+	if _, _, err := parseLevels(levelSpec); err != nil {
+		return err
+	}
+	if format != "" && format != "text" && format != "json" {
+		return fmt.Errorf("logging: unknown format %q: use text or json", format)
+	}
+	return nil
+}
+
+func parseLevels(spec string) (slog.Level, map[string]slog.Level, error) {
+	def := slog.LevelInfo
+	perSubsystem := map[string]slog.Level{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, hasName := strings.Cut(part, "=")
+		if !hasName {
+			value = name
+		}
+
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+			return 0, nil, fmt.Errorf("logging: invalid level %q: %w", part, err)
+		}
+		if hasName {
+			perSubsystem[strings.TrimSpace(name)] = level
+		} else {
+			def = level
+		}
+	}
+	return def, perSubsystem, nil
+}
+
+// For returns the logger of a subsystem. Records carry the subsystem name,
+// and the request id and trace id found in the context.
+func For(subsystem string) *slog.Logger {
+	return slog.New(&handler{subsystem: subsystem})
+}
+
+// handler looks up the root handler and level on every record, so loggers can
+// be created in package variables before Setup runs. WithAttrs and WithGroup
+// are replayed onto the root handler in order.
+type handler struct {
+	subsystem string
+	ops       []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) level() slog.Level {
+	mu.RLock()
+	defer mu.RUnlock()
+	if level, ok := levels[h.subsystem]; ok {
+		return level
+	}
+	return defaultLevel
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level()
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	mu.RLock()
+	out := root
+	mu.RUnlock()
+
+	attrs := []slog.Attr{slog.String("subsystem", h.subsystem)}
+	if id := RequestID(ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		attrs = append(attrs, slog.String("trace_id", span.TraceID().String()))
+	}
+	out = out.WithAttrs(attrs)
+	for _, op := range h.ops {
+		out = op(out)
+	}
+	return out.Handle(ctx, record)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := append(h.ops[:len(h.ops):len(h.ops)], op)
+	return &handler{subsystem: h.subsystem, ops: ops}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose log records carry the request id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request id stored by WithRequestID, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,115 @@
+// Package metrics defines the Prometheus metrics shared by the server and
+// ingest. Everything is registered with the default registry.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "facematch"
+
+var (
+	HttpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HttpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	SidecarDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sidecar_request_duration_seconds",
+		Help:      "Latency of single sidecar embedding requests by endpoint.",
+		Buckets:   []float64{.025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"endpoint"})
+
+	SidecarErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sidecar_errors_total",
+		Help:      "Failed sidecar embedding requests by endpoint and error type.",
+	}, []string{"endpoint", "type"})
+
+	EmbeddingCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "embedding_cache_lookups_total",
+		Help:      "Embedding cache lookups by result (hit or miss).",
+	}, []string{"result"})
+
+	VectorQueryDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "vector_query_duration_seconds",
+		Help:      "Latency of nearest neighbour queries against the images table.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	})
+
+	IngestProcessed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingest_processed_total",
+		Help:      "Images imported successfully.",
+	})
+
+	IngestRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingest_rejected_total",
+		Help:      "Images that failed to import, by reason.",
+	}, []string{"reason"})
+)
+
+// Handler serves the default registry.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// WriteTextfile writes every metric to path in the format read by
+// node-exporter's textfile collector.
+func WriteTextfile(path string) error {
+	return prometheus.WriteToTextfile(path, prometheus.DefaultGatherer)
+}
+
+// RegisterPool exports the pool's connection statistics.
+func RegisterPool(pool *pgxpool.Pool) {
+	prometheus.MustRegister(&poolCollector{pool: pool})
+}
+
+var (
+	poolAcquiredDesc = prometheus.NewDesc(namespace+"_db_pool_acquired_connections", "Connections currently in use.", nil, nil)
+	poolIdleDesc     = prometheus.NewDesc(namespace+"_db_pool_idle_connections", "Connections currently idle.", nil, nil)
+	poolTotalDesc    = prometheus.NewDesc(namespace+"_db_pool_total_connections", "Connections currently open.", nil, nil)
+	poolMaxDesc      = prometheus.NewDesc(namespace+"_db_pool_max_connections", "The most connections the pool may open.", nil, nil)
+	poolAcquireDesc  = prometheus.NewDesc(namespace+"_db_pool_acquires_total", "Connections acquired from the pool.", nil, nil)
+	poolWaitDesc     = prometheus.NewDesc(namespace+"_db_pool_acquire_wait_seconds_total", "Time spent waiting for a connection.", nil, nil)
+)
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredDesc
+	ch <- poolIdleDesc
+	ch <- poolTotalDesc
+	ch <- poolMaxDesc
+	ch <- poolAcquireDesc
+	ch <- poolWaitDesc
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquiredDesc, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalDesc, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxDesc, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDesc, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolWaitDesc, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -1,7 +1,6 @@
 package scrape
 
 import (
-	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -15,7 +14,7 @@ func IsImage(r *colly.Response) bool {
 func SaveImage(r *colly.Response, outDir string, filename string) error {
 	extension := filepath.Ext(r.FileName())
 	outPath := filepath.Join(outDir, sanitize(filename+extension))
-	fmt.Println("Saving image", filename)
+	logger.Info("Saving image", "name", filename, "path", outPath)
 	return r.Save(outPath)
 }
 
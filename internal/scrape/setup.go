@@ -1,18 +1,20 @@
 package scrape
 
 import (
-	"fmt"
 	"time"
 
+	"github.com/face-match/internal/logging"
 	"github.com/gocolly/colly"
 )
 
+var logger = logging.For("scrape")
+
 func SetupBackoff(c *colly.Collector, delay time.Duration) {
 	c.OnError(func(r *colly.Response, e error) {
 		if e.Error() != "Too Many Requests" {
 			return
 		}
-		fmt.Println("Too many requests. Backing off...")
+		logger.Warn("Too many requests, backing off", "url", r.Request.URL.String(), "delay", delay)
 		time.Sleep(delay)
 		r.Request.Retry()
 	})
@@ -28,12 +30,12 @@ func SetupDelay(c *colly.Collector, delay time.Duration) {
 
 func SetupErrorLogging(c *colly.Collector) {
 	c.OnError(func(r *colly.Response, err error) {
-		fmt.Println("Error visiting", r.Request.URL, err)
+		logger.Error("Visit failed", "url", r.Request.URL.String(), "status", r.StatusCode, "error", err)
 	})
 }
 
 func SetupRequestLogging(c *colly.Collector) {
 	c.OnRequest(func(r *colly.Request) {
-		fmt.Println("Visiting", r.URL)
+		logger.Info("Visiting", "url", r.URL.String())
 	})
 }
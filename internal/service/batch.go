@@ -0,0 +1,234 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/imaging"
+	"github.com/face-match/internal/logging"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// Limits the size of a single image unpacked from an uploaded archive.
+	maxArchiveEntrySize = 32 << 20
+
+	// An archive may unpack to this many times the upload limit in total.
+	maxArchiveExpansion = 4
+
+	// MaxBatchImages is the most images a batch may hold.
+	MaxBatchImages = 1000
+)
+
+var (
+	// ErrArchiveTooLarge means an archive unpacks to too many or too large
+	// images.
+	ErrArchiveTooLarge = errors.New("service: archive too large")
+
+	// ErrTooManyImages means a batch holds more than MaxBatchImages images.
+	ErrTooManyImages = errors.New("service: too many images")
+)
+
+var batchLogger = logging.For("batch")
+
+type BatchImage struct {
+	Name  string
+	Bytes []byte
+}
+
+// BatchResult is the outcome for one image of a batch. Error is the stable
+// error code of why it couldn't be searched, as described at ErrorCode.
+type BatchResult struct {
+	Results []SearchResult
+	Error   string
+}
+
+// SearchBatch embeds and searches many images, running at most concurrency
+// sidecar calls at once. A failure for one image is reported in its result
+// rather than failing the batch. Results are keyed by image name; duplicate
+// names get a numeric suffix so that no result is lost.
+func (s *SearchService) SearchBatch(ctx context.Context, categoryIDs []int64, images []BatchImage, concurrency int) map[string]BatchResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	names := uniqueNames(images)
+	results := make([]BatchResult, len(images))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+	for i, img := range images {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				results[i] = BatchResult{Error: ErrorCode(ctx, ctx.Err())}
+				return
+			}
+			defer func() { <-semaphore }()
+
+			found, err := s.Search(ctx, categoryIDs, img.Bytes)
+			if err != nil {
+				results[i] = BatchResult{Error: ErrorCode(ctx, err)}
+				return
+			}
+			results[i] = BatchResult{Results: found}
+		}()
+	}
+	wg.Wait()
+
+	out := make(map[string]BatchResult, len(images))
+	for i, name := range names {
+		out[name] = results[i]
+	}
+	return out
+}
+
+// ErrorCode returns the stable code the API reports for a failed search:
+// low_quality, no_face, unsupported_media_type, image_too_large, bad_image,
+// not_found, canceled, model_mismatch, sidecar_unavailable or
+// internal_error. The detail of unexpected errors is logged rather than
+// shown to clients.
+func ErrorCode(ctx context.Context, err error) string {
+	var lowQuality *ai.ErrLowQuality
+	switch {
+	case errors.As(err, &lowQuality):
+		return "low_quality"
+	case errors.Is(err, ai.ErrNoFace):
+		return "no_face"
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		return "unsupported_media_type"
+	case errors.Is(err, imaging.ErrTooLarge):
+		return "image_too_large"
+	case errors.Is(err, ai.ErrBadImage):
+		return "bad_image"
+	case errors.Is(err, pgx.ErrNoRows):
+		return "not_found"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	case errors.Is(err, ai.ErrModelMismatch):
+		batchLogger.ErrorContext(ctx, "Search failed", "error", err)
+		return "model_mismatch"
+	case errors.Is(err, ai.ErrSidecarUnavailable):
+		batchLogger.ErrorContext(ctx, "Search failed", "error", err)
+		return "sidecar_unavailable"
+	default:
+		batchLogger.ErrorContext(ctx, "Search failed", "error", err)
+		return "internal_error"
+	}
+}
+
+// uniqueNames returns the names of the images, adding " (2)", " (3)" and so
+// on to repeats. A suffixed name is never one that another image already
+// has.
+func uniqueNames(images []BatchImage) []string {
+	taken := make(map[string]bool, len(images))
+	for _, img := range images {
+		taken[img.Name] = true
+	}
+
+	assigned := make(map[string]bool, len(images))
+	next := make(map[string]int)
+	names := make([]string, len(images))
+	for i, img := range images {
+		name := img.Name
+		if assigned[name] {
+			extension := path.Ext(name)
+			base := strings.TrimSuffix(name, extension)
+			n := max(next[img.Name], 2)
+			for {
+				name = fmt.Sprintf("%s (%d)%s", base, n, extension)
+				n++
+				if !taken[name] {
+					break
+				}
+			}
+			next[img.Name] = n
+			taken[name] = true
+		}
+		assigned[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// ReadZipImages unpacks the supported images from a zip archive. Folders
+// inside the archive are kept as part of the image name. Archives with more
+// than MaxBatchImages images, or whose images take more than a few times
+// maxUploadSize, are refused with ErrArchiveTooLarge, and those with an
+// image over the imaging limits with imaging.ErrTooLarge.
+func ReadZipImages(data []byte, maxUploadSize int64) ([]BatchImage, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("service: open zip: %w", err)
+	}
+
+	// The sizes in the archive may lie, so the budget is charged with what
+	// is actually read
+	budget := maxUploadSize * maxArchiveExpansion
+	var images []BatchImage
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() || !imaging.IsSupportedFile(f.Name) {
+			continue
+		}
+		if len(images) == MaxBatchImages {
+			return nil, fmt.Errorf("%w: more than %d images", ErrArchiveTooLarge, MaxBatchImages)
+		}
+		limit := min(maxArchiveEntrySize, budget)
+		if f.UncompressedSize64 > uint64(limit) {
+			return nil, fmt.Errorf("%w: zip entry %s is too large", ErrArchiveTooLarge, f.Name)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("service: open zip entry %s: %w", f.Name, err)
+		}
+		imageBytes, err := io.ReadAll(io.LimitReader(rc, limit+1))
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("service: read zip entry %s: %w", f.Name, err)
+		}
+		if int64(len(imageBytes)) > limit {
+			return nil, fmt.Errorf("%w: zip entry %s is too large", ErrArchiveTooLarge, f.Name)
+		}
+		budget -= int64(len(imageBytes))
+
+		// Other failures are reported in the image's result, like those of
+		// uploaded files
+		if _, _, err := imaging.Check(imageBytes); errors.Is(err, imaging.ErrTooLarge) {
+			return nil, fmt.Errorf("service: zip entry %s: %w", f.Name, err)
+		}
+		images = append(images, BatchImage{Name: f.Name, Bytes: imageBytes})
+	}
+	return images, nil
+}
+
+// ReadImageDir loads the supported images directly inside dir, sorted by
+// name. Files over maxSize bytes are an error.
+func ReadImageDir(dir string, maxSize int64) ([]BatchImage, error) {
+	files, err := listImageFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("service: list images: %w", err)
+	}
+
+	images := make([]BatchImage, 0, len(files))
+	for _, f := range files {
+		imageBytes, err := imaging.ReadFile(filepath.Join(dir, f), maxSize)
+		if err != nil {
+			return nil, fmt.Errorf("service: read image: %w", err)
+		}
+		images = append(images, BatchImage{Name: f, Bytes: imageBytes})
+	}
+	return images, nil
+}
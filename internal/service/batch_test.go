@@ -0,0 +1,154 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"slices"
+	"testing"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/imaging"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestUniqueNames(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  []string
+	}{
+		{[]string{"a.jpg", "b.jpg"}, []string{"a.jpg", "b.jpg"}},
+		{[]string{"a.jpg", "a.jpg", "a.jpg"}, []string{"a.jpg", "a (2).jpg", "a (3).jpg"}},
+		{[]string{"a.jpg", "a.jpg", "a (2).jpg"}, []string{"a.jpg", "a (3).jpg", "a (2).jpg"}},
+		{[]string{"a (2).jpg", "a.jpg", "a.jpg"}, []string{"a (2).jpg", "a.jpg", "a (3).jpg"}},
+		{[]string{"a.jpg", "a.jpg", "a (2).jpg", "a (2).jpg"}, []string{"a.jpg", "a (3).jpg", "a (2).jpg", "a (2) (2).jpg"}},
+		{[]string{"x", "x"}, []string{"x", "x (2)"}},
+	}
+	for _, test := range tests {
+		images := make([]BatchImage, len(test.names))
+		for i, name := range test.names {
+			images[i] = BatchImage{Name: name}
+		}
+
+		got := uniqueNames(images)
+		if !slices.Equal(got, test.want) {
+			t.Errorf("uniqueNames(%q) = %q, want %q", test.names, got, test.want)
+		}
+		seen := map[string]bool{}
+		for _, name := range got {
+			if seen[name] {
+				t.Errorf("uniqueNames(%q) repeats %q", test.names, name)
+			}
+			seen[name] = true
+		}
+	}
+}
+
+// zipOf archives the files, keyed by name.
+func zipOf(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func smallPng(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadZipImages(t *testing.T) {
+	img := smallPng(t, 8, 8)
+	archive := zipOf(t, map[string][]byte{
+		"a.png":       img,
+		"dir/b.png":   img,
+		"notes.txt":   []byte("skipped"),
+		"corrupt.jpg": []byte("reported in its result"),
+	})
+	images, err := ReadZipImages(archive, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 3 {
+		t.Errorf("ReadZipImages() returned %d images, want 3", len(images))
+	}
+}
+
+func TestReadZipImagesLimits(t *testing.T) {
+	// Compresses to almost nothing
+	zeros := make([]byte, 3<<20)
+
+	tooMany := map[string][]byte{}
+	for i := 0; i <= MaxBatchImages; i++ {
+		tooMany[fmt.Sprintf("%d.png", i)] = nil
+	}
+
+	// Declares 100000x100000 pixels in a few bytes
+	huge := smallPng(t, 1, 1)
+	binary.BigEndian.PutUint32(huge[16:20], 100_000)
+	binary.BigEndian.PutUint32(huge[20:24], 100_000)
+	binary.BigEndian.PutUint32(huge[29:33], crc32.ChecksumIEEE(huge[12:29]))
+
+	tests := []struct {
+		name  string
+		files map[string][]byte
+		want  error
+	}{
+		{"over the total", map[string][]byte{"a.png": zeros, "b.png": zeros}, ErrArchiveTooLarge},
+		{"one entry over the total", map[string][]byte{"a.png": make([]byte, 5<<20)}, ErrArchiveTooLarge},
+		{"too many images", tooMany, ErrArchiveTooLarge},
+		{"image over the limits", map[string][]byte{"huge.png": huge}, imaging.ErrTooLarge},
+	}
+	for _, test := range tests {
+		// Unpacking to four times the limit is allowed, so 4MB here
+		_, err := ReadZipImages(zipOf(t, test.files), 1<<20)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: ReadZipImages() = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("fetch embedding: %w", &ai.ErrLowQuality{Reason: "sharpness"}), "low_quality"},
+		{fmt.Errorf("fetch embedding: %w", ai.ErrNoFace), "no_face"},
+		{imaging.ErrUnsupportedFormat, "unsupported_media_type"},
+		{imaging.ErrTooLarge, "image_too_large"},
+		{ai.ErrBadImage, "bad_image"},
+		{pgx.ErrNoRows, "not_found"},
+		{context.Canceled, "canceled"},
+		{ai.ErrModelMismatch, "model_mismatch"},
+		{fmt.Errorf("%w: dial tcp 10.0.0.1:8000: connection refused", ai.ErrSidecarUnavailable), "sidecar_unavailable"},
+		{errors.New("search: failed to connect to `user=facematch database=facematch`"), "internal_error"},
+	}
+	for _, test := range tests {
+		if got := ErrorCode(context.Background(), test.err); got != test.want {
+			t.Errorf("ErrorCode(%v) = %q, want %q", test.err, got, test.want)
+		}
+	}
+}
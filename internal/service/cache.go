@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/store"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EmbeddingCache keeps embeddings in Postgres, so an interrupted ingest can
+// be re-run without embedding the same files again.
+type EmbeddingCache struct {
+	cacheStore *store.EmbeddingCacheStore
+}
+
+func NewEmbeddingCache(pool *pgxpool.Pool) *EmbeddingCache {
+	return &EmbeddingCache{cacheStore: store.NewEmbeddingCacheStore(pool)}
+}
+
+func (cache *EmbeddingCache) Get(ctx context.Context, key ai.CacheKey, model string) (*ai.CacheEntry, error) {
+	cached, err := cache.cacheStore.Fetch(ctx, key[:], model)
+	if err != nil || cached == nil {
+		return nil, err
+	}
+	return &ai.CacheEntry{
+		Model:     cached.Model,
+		Embedding: cached.Embedding,
+		BBox:      cached.BBox,
+		DetScore:  cached.DetScore,
+	}, nil
+}
+
+func (cache *EmbeddingCache) Put(ctx context.Context, key ai.CacheKey, entry *ai.CacheEntry) error {
+	return cache.cacheStore.Upsert(ctx, &store.CachedEmbedding{
+		ContentHash: key[:],
+		Model:       entry.Model,
+		Embedding:   entry.Embedding,
+		BBox:        entry.BBox,
+		DetScore:    entry.DetScore,
+	})
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/logging"
+	"github.com/face-match/internal/store"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var canaryLogger = logging.For("canary")
+
+// DefaultCanaryThreshold is the cosine distance beyond which a canary's
+// embedding is considered to have drifted. Re-running the same model on the
+// same providers should stay well below it.
+const DefaultCanaryThreshold = 0.01
+
+// ErrCanaryDrift is returned by Check when any canary drifted or failed.
+var ErrCanaryDrift = errors.New("service: embedding drift detected")
+
+// CanaryResult is the outcome of re-embedding one canary.
+type CanaryResult struct {
+	Name    string
+	Drift   float64 // cosine distance from the registered embedding
+	Drifted bool
+	Error   string
+}
+
+type CanaryService struct {
+	canaryStore  *store.CanaryStore
+	modelService *ModelService
+	aiClient     *ai.Client
+}
+
+func NewCanaryService(pool *pgxpool.Pool, aiClient *ai.Client) *CanaryService {
+	return &CanaryService{
+		canaryStore:  store.NewCanaryStore(pool),
+		modelService: NewModelService(pool, aiClient),
+		aiClient:     aiClient,
+	}
+}
+
+// Register embeds the image and stores it as a canary under the name. The
+// image is stored as sent to the sidecar, so later checks send identical
+// bytes.
+func (service *CanaryService) Register(ctx context.Context, name string, imageBytes []byte) error {
+	model, err := service.modelService.Verify(ctx)
+	if err != nil {
+		return err
+	}
+
+	decoded, err := decodeImage(imageBytes)
+	if err != nil {
+		return err
+	}
+	uploads, err := decoded.Uploads(imageBytes)
+	if err != nil {
+		return fmt.Errorf("prepare upload: %w", err)
+	}
+	upload := uploads[0].Bytes
+
+	face, err := service.aiClient.Embed(ctx, upload)
+	if err != nil {
+		return err
+	}
+
+	_, err = service.canaryStore.Upsert(ctx, &store.Canary{
+		Name:       name,
+		ImageBytes: upload,
+		Embedding:  face.Embedding,
+		ModelID:    model.ID,
+	})
+	return err
+}
+
+func (service *CanaryService) List(ctx context.Context) ([]store.Canary, error) {
+	return service.canaryStore.List(ctx)
+}
+
+func (service *CanaryService) Remove(ctx context.Context, name string) error {
+	return service.canaryStore.Delete(ctx, name)
+}
+
+// Check re-embeds every canary registered with the active model, bypassing
+// the embedding cache, and records the drift. It returns ErrCanaryDrift
+// along with the results when any canary exceeds the threshold or fails.
+func (service *CanaryService) Check(ctx context.Context, threshold float64) ([]CanaryResult, error) {
+	model, err := service.modelService.Verify(ctx)
+	if err != nil {
+		return nil, err
+	}
+	canaries, err := service.canaryStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []CanaryResult
+	failed := false
+	for _, canary := range canaries {
+		if canary.ModelID != model.ID {
+			// Registered with a previous model; its embedding isn't comparable.
+			continue
+		}
+
+		result := CanaryResult{Name: canary.Name}
+		check := &store.CanaryCheck{CanaryID: canary.ID}
+
+		face, err := service.aiClient.Embed(ctx, canary.ImageBytes)
+		if err != nil {
+			if ctx.Err() != nil {
+				return results, ctx.Err()
+			}
+			result.Error = err.Error()
+			check.Error = result.Error
+		} else {
+			result.Drift = cosineDistance(canary.Embedding, face.Embedding)
+			result.Drifted = result.Drift > threshold
+			check.Drift = &result.Drift
+		}
+		if result.Drifted || result.Error != "" {
+			failed = true
+		}
+
+		if err := service.canaryStore.InsertCheck(ctx, check); err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+
+	if failed {
+		return results, ErrCanaryDrift
+	}
+	return results, nil
+}
+
+// Run checks the canaries every interval until the context is cancelled,
+// logging an alert for each canary that drifted.
+func (service *CanaryService) Run(ctx context.Context, interval time.Duration, threshold float64) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		results, err := service.Check(ctx, threshold)
+		if err != nil && !errors.Is(err, ErrCanaryDrift) {
+			if ctx.Err() == nil {
+				canaryLogger.ErrorContext(ctx, "Canary check failed", "error", err)
+			}
+			continue
+		}
+		for _, result := range results {
+			switch {
+			case result.Error != "":
+				canaryLogger.ErrorContext(ctx, "Canary could not be embedded", "alert", true, "canary", result.Name, "error", result.Error)
+			case result.Drifted:
+				canaryLogger.ErrorContext(ctx, "Canary drifted", "alert", true, "canary", result.Name, "drift", result.Drift, "threshold", threshold)
+			}
+		}
+	}
+}
+
+// cosineDistance is 1 - cosine similarity, matching pgvector's <=> operator.
+func cosineDistance(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 1
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 1
+	}
+	return 1 - dot/(math.Sqrt(normA)*math.Sqrt(normB))
+}
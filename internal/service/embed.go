@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/imaging"
+)
+
+// decodeImage decodes the image once for everything that needs its pixels.
+// Images refused for their type or size keep the imaging error; anything
+// else unreadable is an ai.ErrBadImage.
+func decodeImage(imageBytes []byte) (*imaging.Decoded, error) {
+	decoded, err := imaging.Decode(imageBytes)
+	switch {
+	case errors.Is(err, imaging.ErrUnsupportedFormat), errors.Is(err, imaging.ErrTooLarge):
+		return nil, fmt.Errorf("decode image: %w", err)
+	case err != nil:
+		return nil, fmt.Errorf("decode image: %w: %w", ai.ErrBadImage, err)
+	}
+	return decoded, nil
+}
+
+// fetchEmbedding uploads a working copy of the upright image to the sidecar.
+// For animations, every sampled frame is uploaded and the most confident
+// face is kept.
+func fetchEmbedding(ctx context.Context, client *ai.Client, decoded *imaging.Decoded, imageBytes []byte, thresholds ai.QualityThresholds) ([]float32, error) {
+	if client == nil {
+		return nil, fmt.Errorf("AIEndpoint is required")
+	}
+
+	uploads, err := decoded.Uploads(imageBytes)
+	if err != nil {
+		return nil, fmt.Errorf("prepare upload: %w", err)
+	}
+
+	var best *ai.Face
+	var firstErr error
+	for i := range uploads {
+		face, err := client.FetchFace(ctx, &uploads[i], thresholds)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if best == nil || face.DetScore > best.DetScore {
+			best = face
+		}
+	}
+	if best == nil {
+		return nil, firstErr
+	}
+	return best.Embedding, nil
+}
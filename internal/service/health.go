@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/store"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// How long a readiness report is reused, so frequent probes don't
+	// hammer the database and sidecar.
+	readinessCacheFor = 5 * time.Second
+
+	readinessTimeout = 5 * time.Second
+)
+
+// Check is the outcome of checking one dependency.
+type Check struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type Readiness struct {
+	Ready     bool             `json:"ready"`
+	CheckedAt time.Time        `json:"checked_at"`
+	Checks    map[string]Check `json:"checks"`
+}
+
+type HealthService struct {
+	healthStore *store.HealthStore
+	modelStore  *store.ModelStore
+	aiClient    *ai.Client
+
+	mu   sync.Mutex
+	last *Readiness
+}
+
+func NewHealthService(pool *pgxpool.Pool, aiClient *ai.Client) *HealthService {
+	return &HealthService{
+		healthStore: store.NewHealthStore(pool),
+		modelStore:  store.NewModelStore(pool),
+		aiClient:    aiClient,
+	}
+}
+
+// Readiness checks the database, its migrations and search index, and the
+// sidecar and its model. Reports are cached briefly; concurrent callers
+// share one round of checks.
+func (service *HealthService) Readiness(ctx context.Context) *Readiness {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.last != nil && time.Since(service.last.CheckedAt) < readinessCacheFor {
+		return service.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	readiness := &Readiness{Ready: true, CheckedAt: time.Now(), Checks: map[string]Check{}}
+	record := func(name string, err error) {
+		check := Check{Ok: err == nil}
+		if err != nil {
+			check.Error = err.Error()
+			readiness.Ready = false
+		}
+		readiness.Checks[name] = check
+	}
+
+	dbErr := service.healthStore.Ping(ctx)
+	record("database", dbErr)
+	if dbErr == nil {
+		record("migrations", service.checkMigrations(ctx))
+		record("index", service.checkIndex(ctx))
+	}
+	record("sidecar", service.checkSidecar(ctx, dbErr == nil))
+
+	service.last = readiness
+	return readiness
+}
+
+func (service *HealthService) checkMigrations(ctx context.Context) error {
+	latest, err := store.LatestMigration()
+	if err != nil {
+		return err
+	}
+	applied, err := service.healthStore.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if applied < latest {
+		return fmt.Errorf("database is at migration %d, this build needs %d", applied, latest)
+	}
+	return nil
+}
+
+func (service *HealthService) checkIndex(ctx context.Context) error {
+	valid, err := service.healthStore.IndexValid(ctx, store.EmbeddingIndex)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("index %s is missing or invalid", store.EmbeddingIndex)
+	}
+	return nil
+}
+
+// checkSidecar needs a sidecar running the handshake's model, and that model
+// to be the database's active one when the database can be asked.
+func (service *HealthService) checkSidecar(ctx context.Context, checkActive bool) error {
+	if service.aiClient == nil {
+		return fmt.Errorf("AIEndpoint is required")
+	}
+	if err := service.aiClient.Ready(ctx); err != nil {
+		return err
+	}
+	if !checkActive {
+		return nil
+	}
+
+	active, err := service.modelStore.FetchActive(ctx)
+	if err != nil {
+		return err
+	}
+	info := service.aiClient.Info()
+	if active == nil || active.Name != info.Name || active.Version != info.Version {
+		return fmt.Errorf("%w: sidecar runs %s, which is not the active model", ai.ErrModelMismatch, info.ID())
+	}
+	return nil
+}
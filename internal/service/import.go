@@ -2,8 +2,8 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"slices"
@@ -13,23 +13,35 @@ import (
 	"github.com/face-match/internal/ai"
 	"github.com/face-match/internal/app"
 	"github.com/face-match/internal/hash"
+	"github.com/face-match/internal/imaging"
+	"github.com/face-match/internal/logging"
+	"github.com/face-match/internal/metrics"
 	"github.com/face-match/internal/store"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var importLogger = logging.For("import")
+
+// ErrAlreadyProcessed is returned when the same image was enrolled before.
+var ErrAlreadyProcessed = errors.New("service: image already processed")
+
 type ImportService struct {
 	config        *app.Config
+	aiClient      *ai.Client
 	categoryStore *store.CategoryStore
 	imageStore    *store.ImageStore
 	personStore   *store.PersonStore
+	modelService  *ModelService
 }
 
-func NewImportService(config *app.Config, pool *pgxpool.Pool) *ImportService {
+func NewImportService(config *app.Config, pool *pgxpool.Pool, aiClient *ai.Client) *ImportService {
 	return &ImportService{
 		config:        config,
+		aiClient:      aiClient,
 		categoryStore: store.NewCategoryStore(pool),
 		imageStore:    store.NewImageStore(pool),
 		personStore:   store.NewPersonStore(pool),
+		modelService:  NewModelService(pool, aiClient),
 	}
 }
 
@@ -39,23 +51,49 @@ func (service *ImportService) Import(ctx context.Context, category string) error
 		return fmt.Errorf("service: fetch category id: %w", err)
 	}
 
+	model, err := service.modelService.Verify(ctx)
+	if err != nil {
+		return fmt.Errorf("service: verify model: %w", err)
+	}
+
 	files, err := fetchInputFiles(service.config)
 	if err != nil {
 		return fmt.Errorf("service: fetch files: %w", err)
 	}
-	log.Printf("Importing %d file(s) from %s into category id %d",
-		len(files), service.config.InputPath, categoryId)
+	importLogger.InfoContext(ctx, "Importing files", "count", len(files), "dir", service.config.InputPath, "category_id", categoryId)
 
+	thresholds := service.config.Quality.EnrollmentFor(category)
 	for _, f := range files {
-		if err := processFile(ctx, service, categoryId, f); err != nil {
-			log.Printf("Error processing file %s: %v", f, err)
+		if err := processFile(ctx, service, categoryId, model.ID, thresholds, f); err != nil {
+			reason := rejectReason(err)
+			metrics.IngestRejected.WithLabelValues(reason).Inc()
+			importLogger.WarnContext(ctx, "File rejected", "file", f, "reason", reason, "error", err)
+			continue
 		}
+		metrics.IngestProcessed.Inc()
 	}
 	return nil
 }
 
+// rejectReason labels why a file couldn't be imported.
+func rejectReason(err error) string {
+	switch {
+	case errors.Is(err, ErrAlreadyProcessed):
+		return "duplicate"
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		return "unsupported_format"
+	case errors.Is(err, imaging.ErrTooLarge), errors.Is(err, imaging.ErrFileTooLarge):
+		return "too_large"
+	}
+	return ai.ErrorKind(err)
+}
+
 func fetchInputFiles(config *app.Config) ([]string, error) {
-	files, err := os.ReadDir(config.InputPath)
+	return listImageFiles(config.InputPath)
+}
+
+func listImageFiles(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
@@ -65,11 +103,8 @@ func fetchInputFiles(config *app.Config) ([]string, error) {
 		if f.IsDir() {
 			continue
 		}
-		extension := strings.ToLower(filepath.Ext(f.Name()))
-		switch extension {
-		case ".jpg", ".jpeg", ".png", ".webp":
+		if imaging.IsSupportedFile(f.Name()) {
 			imageFiles = append(imageFiles, f.Name())
-		default:
 		}
 	}
 
@@ -79,20 +114,71 @@ func fetchInputFiles(config *app.Config) ([]string, error) {
 	return imageFiles, nil
 }
 
-func processFile(ctx context.Context, service *ImportService, categoryId int64, filename string) error {
+func processFile(ctx context.Context, service *ImportService, categoryId int64, modelID int64, thresholds ai.QualityThresholds, filename string) error {
 	name, tag, err := parseInboxFilename(filename)
 	if err != nil {
 		return fmt.Errorf("service: parse inbox filename: %w", err)
 	}
 
-	imageBytes, err := os.ReadFile(filepath.Join(service.config.InputPath, filename))
+	imageBytes, err := imaging.ReadFile(filepath.Join(service.config.InputPath, filename), service.config.MaxUploadSize)
 	if err != nil {
 		return fmt.Errorf("read file: %w", err)
 	}
 
-	embedding, err := ai.FetchEmbedding(ctx, service.config.AIEndpoint, imageBytes)
+	enrollment, err := service.enroll(ctx, categoryId, modelID, thresholds, name, tag, imageBytes)
+	if err != nil {
+		return err
+	}
+
+	// Move/create files:
+	if err := os.Rename(filepath.Join(service.config.InputPath, filename), filepath.Join(service.config.FinishedPath, filename)); err != nil {
+		return fmt.Errorf("move to ok: %w", err)
+	}
+
+	importLogger.InfoContext(ctx, "File imported", "file", filename, "category_id", categoryId, "person_id", enrollment.PersonID, "image_id", enrollment.ImageID)
+	return nil
+}
+
+// Enrollment identifies an enrolled image and the person it shows.
+type Enrollment struct {
+	PersonID int64
+	ImageID  int64
+}
+
+// Enroll adds one image of a person to a category, creating the person if
+// they're new. It applies the same checks as Import.
+func (service *ImportService) Enroll(ctx context.Context, category string, name string, tag string, imageBytes []byte) (*Enrollment, error) {
+	categoryId, err := service.categoryStore.FetchId(ctx, category)
+	if err != nil {
+		return nil, fmt.Errorf("service: fetch category id: %w", err)
+	}
+
+	model, err := service.modelService.Verify(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("service: verify model: %w", err)
+	}
+
+	thresholds := service.config.Quality.EnrollmentFor(category)
+	enrollment, err := service.enroll(ctx, categoryId, model.ID, thresholds, name, tag, imageBytes)
+	if err != nil {
+		reason := rejectReason(err)
+		metrics.IngestRejected.WithLabelValues(reason).Inc()
+		return nil, err
+	}
+	metrics.IngestProcessed.Inc()
+
+	importLogger.InfoContext(ctx, "Image enrolled", "category_id", categoryId, "person_id", enrollment.PersonID, "image_id", enrollment.ImageID)
+	return enrollment, nil
+}
+
+func (service *ImportService) enroll(ctx context.Context, categoryId int64, modelID int64, thresholds ai.QualityThresholds, name string, tag string, imageBytes []byte) (*Enrollment, error) {
+	decoded, err := decodeImage(imageBytes)
 	if err != nil {
-		return fmt.Errorf("fetch embedding: %w", err)
+		return nil, err
+	}
+	embedding, err := fetchEmbedding(ctx, service.aiClient, decoded, imageBytes, thresholds)
+	if err != nil {
+		return nil, fmt.Errorf("fetch embedding: %w", err)
 	}
 
 	// Save person to database:
@@ -105,21 +191,18 @@ func processFile(ctx context.Context, service *ImportService, categoryId int64,
 	}
 	personID, err := service.personStore.Upsert(ctx, &person)
 	if err != nil {
-		return fmt.Errorf("upsert person: %w", err)
+		return nil, fmt.Errorf("upsert person: %w", err)
 	}
 
 	// Save image to database:
 
-	imageHash, err := hash.DHash64(imageBytes)
-	if err != nil {
-		return fmt.Errorf("hash image: %w", err)
-	}
+	imageHash := hash.DHash64FromImage(decoded.First())
 	exists, err := service.imageStore.VerifyNoHash(ctx, imageHash)
 	if err != nil {
-		return fmt.Errorf("fetch id by hash: %w", err)
+		return nil, fmt.Errorf("fetch id by hash: %w", err)
 	}
 	if exists {
-		return fmt.Errorf("image already processed")
+		return nil, ErrAlreadyProcessed
 	}
 
 	image := store.Image{
@@ -127,19 +210,13 @@ func processFile(ctx context.Context, service *ImportService, categoryId int64,
 		PersonID:   personID,
 		ImageHash:  imageHash,
 		Embedding:  embedding,
+		ModelID:    modelID,
 	}
 	imageID, err := service.imageStore.Insert(ctx, &image)
 	if err != nil {
-		return fmt.Errorf("insert image: %w", err)
-	}
-
-	// Move/create files:
-	if err := os.Rename(filepath.Join(service.config.InputPath, filename), filepath.Join(service.config.FinishedPath, filename)); err != nil {
-		return fmt.Errorf("move to ok: %w", err)
+		return nil, fmt.Errorf("insert image: %w", err)
 	}
-
-	log.Printf("OK: %s => person_id=%d image_id=%d", filename, personID, imageID)
-	return nil
+	return &Enrollment{PersonID: personID, ImageID: imageID}, nil
 }
 
 // Expected formats:
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/app"
+	"github.com/face-match/internal/logging"
+	"github.com/face-match/internal/store"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// How long an idle worker waits before looking for new jobs.
+	jobPollInterval = 2 * time.Second
+
+	// How often a worker renews the heartbeat of the job it runs.
+	jobHeartbeatInterval = 30 * time.Second
+
+	// Running jobs whose heartbeat is older than this are assumed lost (e.g.
+	// the server restarted) and are picked up again.
+	jobStaleAfter = 4 * jobHeartbeatInterval
+
+	// A job claimed more often than this, its workers having died or lost
+	// it every time, is failed rather than run again.
+	jobMaxAttempts = 3
+
+	webhookAttempts = 3
+	webhookTimeout  = 10 * time.Second
+)
+
+var jobLogger = logging.For("jobs")
+
+var ErrInvalidWebhookUrl = errors.New("service: invalid webhook url")
+
+// Job is the public view of a search job.
+type Job struct {
+	ID         string
+	Status     string
+	CreatedAt  time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	Results    map[string]BatchResult
+	Error      string
+}
+
+type JobService struct {
+	config        *app.Config
+	jobStore      *store.JobStore
+	searchService *SearchService
+	webhooks      webhookGuard
+	client        *http.Client
+}
+
+func NewJobService(config *app.Config, pool *pgxpool.Pool, aiClient *ai.Client) *JobService {
+	webhooks := webhookGuard{allowed: config.WebhookAllowedNetworks}
+	return &JobService{
+		config:        config,
+		jobStore:      store.NewJobStore(pool),
+		searchService: NewSearchService(config, pool, aiClient),
+		webhooks:      webhooks,
+		client:        webhooks.client(webhookTimeout),
+	}
+}
+
+// Submit queues a search of the images and returns the job id. When
+// webhookUrl is set, the finished job is POSTed to it; it may not point at
+// a loopback, private or link-local address unless that's explicitly
+// allowed.
+func (service *JobService) Submit(ctx context.Context, categoryIDs []int64, images []BatchImage, webhookUrl string) (string, error) {
+	if webhookUrl != "" {
+		if err := service.webhooks.checkUrl(ctx, webhookUrl); err != nil {
+			return "", err
+		}
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return "", fmt.Errorf("service: job id: %w", err)
+	}
+
+	jobImages := make([]store.JobImage, 0, len(images))
+	for _, img := range images {
+		jobImages = append(jobImages, store.JobImage{Name: img.Name, ImageBytes: img.Bytes})
+	}
+
+	job := store.Job{
+		ID:          id,
+		CategoryIDs: categoryIDs,
+		WebhookUrl:  webhookUrl,
+	}
+	if err := service.jobStore.Insert(ctx, &job, jobImages); err != nil {
+		return "", fmt.Errorf("service: submit job: %w", err)
+	}
+	return id, nil
+}
+
+func (service *JobService) Fetch(ctx context.Context, id string) (*Job, error) {
+	job, err := service.jobStore.FetchById(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("service: fetch job: %w", err)
+	}
+	return toJob(job)
+}
+
+// Run processes queued jobs with the given number of workers until ctx is
+// cancelled.
+func (service *JobService) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			service.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (service *JobService) work(ctx context.Context) {
+	for {
+		claimToken, err := newJobID()
+		if err != nil {
+			jobLogger.ErrorContext(ctx, "Claiming a job failed", "error", err)
+			return
+		}
+		job, err := service.jobStore.Claim(ctx, claimToken, jobStaleAfter)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			jobLogger.ErrorContext(ctx, "Claiming a job failed", "error", err)
+		}
+		if job != nil {
+			service.process(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+func (service *JobService) process(ctx context.Context, job *store.Job) {
+	if job.Attempts > jobMaxAttempts {
+		service.finish(ctx, job, nil, fmt.Errorf("service: job abandoned after %d attempts", jobMaxAttempts))
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		service.heartbeat(jobCtx, cancel, job)
+	}()
+	results, jobErr := service.search(jobCtx, job)
+	lost := jobCtx.Err() != nil
+	cancel()
+	<-heartbeatDone
+	if lost {
+		// Shutting down, in which case the job is picked up again once
+		// stale, or another worker took it over.
+		return
+	}
+	service.finish(ctx, job, results, jobErr)
+}
+
+// finish records the outcome of a job, drops its images unless they're
+// retained, and calls its webhook.
+func (service *JobService) finish(ctx context.Context, job *store.Job, results []byte, jobErr error) {
+	var errorText string
+	if jobErr != nil {
+		errorText = jobErr.Error()
+		jobLogger.WarnContext(ctx, "Job failed", "job_id", job.ID, "error", jobErr)
+	}
+	finished, err := service.jobStore.Finish(ctx, job.ID, job.ClaimToken, results, errorText)
+	if err != nil {
+		jobLogger.ErrorContext(ctx, "Finishing the job failed", "job_id", job.ID, "error", err)
+		return
+	}
+	if !finished {
+		jobLogger.WarnContext(ctx, "Job was taken over by another worker", "job_id", job.ID)
+		return
+	}
+	if service.config.QueryRetention != app.RetentionFull {
+		// The retention cleanup retries this if it fails
+		if err := service.jobStore.DeleteImages(ctx, job.ID); err != nil {
+			jobLogger.ErrorContext(ctx, "Deleting the job images failed", "job_id", job.ID, "error", err)
+		}
+	}
+
+	if job.WebhookUrl != "" {
+		service.notify(ctx, job.ID)
+	}
+}
+
+// heartbeat renews the job's heartbeat until ctx is done. When another
+// worker has taken the job over, it calls cancel so that the job isn't
+// finished, and its webhook called, twice.
+func (service *JobService) heartbeat(ctx context.Context, cancel context.CancelFunc, job *store.Job) {
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		owned, err := service.jobStore.Heartbeat(ctx, job.ID, job.ClaimToken)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			// Retried on the next tick; the job only goes stale after a few
+			jobLogger.WarnContext(ctx, "Job heartbeat failed", "job_id", job.ID, "error", err)
+		case err == nil && !owned:
+			jobLogger.WarnContext(ctx, "Job was taken over by another worker", "job_id", job.ID)
+			cancel()
+			return
+		}
+	}
+}
+
+func (service *JobService) search(ctx context.Context, job *store.Job) ([]byte, error) {
+	jobImages, err := service.jobStore.FetchImages(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]BatchImage, 0, len(jobImages))
+	for _, img := range jobImages {
+		images = append(images, BatchImage{Name: img.Name, Bytes: img.ImageBytes})
+	}
+
+	results := service.searchService.SearchBatch(ctx, job.CategoryIDs, images, 1)
+	return json.Marshal(results)
+}
+
+// notify POSTs the finished job to its webhook, retrying a few times, and
+// records how delivery went.
+func (service *JobService) notify(ctx context.Context, id string) {
+	job, err := service.jobStore.FetchById(ctx, id)
+	if err != nil {
+		jobLogger.ErrorContext(ctx, "Webhook failed", "job_id", id, "error", err)
+		return
+	}
+	view, err := toJob(job)
+	if err != nil {
+		jobLogger.ErrorContext(ctx, "Webhook failed", "job_id", id, "error", err)
+		return
+	}
+	body, err := json.Marshal(view)
+	if err != nil {
+		jobLogger.ErrorContext(ctx, "Webhook failed", "job_id", id, "error", err)
+		return
+	}
+
+	status := "delivered"
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		err = service.postWebhook(ctx, job.WebhookUrl, body)
+		if err == nil {
+			break
+		}
+		status = fmt.Sprintf("failed: %v", err)
+		jobLogger.WarnContext(ctx, "Webhook attempt failed", "job_id", id, "attempt", attempt, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+
+	if err := service.jobStore.SetWebhookStatus(ctx, id, status); err != nil {
+		jobLogger.ErrorContext(ctx, "Recording the webhook status failed", "job_id", id, "error", err)
+	}
+}
+
+func (service *JobService) postWebhook(ctx context.Context, webhookUrl string, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := service.client.Do(request)
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", response.Status)
+	}
+	return nil
+}
+
+func toJob(job *store.Job) (*Job, error) {
+	out := &Job{
+		ID:         job.ID,
+		Status:     job.Status,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+		Error:      job.Error,
+	}
+	if job.Results != nil {
+		if err := json.Unmarshal(job.Results, &out.Results); err != nil {
+			return nil, fmt.Errorf("service: decode job results: %w", err)
+		}
+	}
+	return out, nil
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/logging"
+	"github.com/face-match/internal/store"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var modelLogger = logging.For("models")
+
+// ModelStatus describes the sidecar's model and the one the database's
+// embeddings came from.
+type ModelStatus struct {
+	Sidecar     *ai.ModelInfo
+	ActiveModel *store.Model
+}
+
+type ModelService struct {
+	modelStore *store.ModelStore
+	aiClient   *ai.Client
+}
+
+func NewModelService(pool *pgxpool.Pool, aiClient *ai.Client) *ModelService {
+	return &ModelService{
+		modelStore: store.NewModelStore(pool),
+		aiClient:   aiClient,
+	}
+}
+
+// Verify checks that the sidecar's model can produce embeddings comparable to
+// the stored ones, and returns the active model. The first model seen is
+// recorded as active. Any other model is refused with ai.ErrModelMismatch
+// until it is activated explicitly.
+func (service *ModelService) Verify(ctx context.Context) (*store.Model, error) {
+	info, err := service.sidecarInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.Dim != store.EmbeddingDim {
+		return nil, fmt.Errorf("%w: sidecar model %s has %d dimensions, the database stores %d",
+			ai.ErrModelMismatch, info.ID(), info.Dim, store.EmbeddingDim)
+	}
+
+	active, err := service.modelStore.FetchActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if active == nil {
+		modelLogger.InfoContext(ctx, "Recording the active model", "model", info.ID())
+		model, err := service.activate(ctx, info)
+		if err != nil {
+			return nil, err
+		}
+		service.aiClient.MarkVerified()
+		return model, nil
+	}
+
+	if active.Name != info.Name || active.Version != info.Version || active.Dim != info.Dim || active.Normalization != info.Normalization {
+		return nil, fmt.Errorf("%w: sidecar runs %s, the database's embeddings are from %s@%s",
+			ai.ErrModelMismatch, info.ID(), active.Name, active.Version)
+	}
+	service.aiClient.MarkVerified()
+	return active, nil
+}
+
+// VerifyUntilDone retries Verify every interval until it succeeds or ctx is
+// cancelled, handshaking again each time in case the sidecar's model was
+// changed. Meanwhile a client that requires verification refuses to embed.
+func (service *ModelService) VerifyUntilDone(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, err := service.aiClient.Handshake(ctx)
+		var model *store.Model
+		if err == nil {
+			model, err = service.Verify(ctx)
+		}
+		if err == nil {
+			modelLogger.InfoContext(ctx, "Sidecar model verified", "model", model.Name+"@"+model.Version)
+			return
+		}
+		if ctx.Err() == nil {
+			modelLogger.WarnContext(ctx, "Sidecar model still not verified", "error", err)
+		}
+	}
+}
+
+// Activate makes the sidecar's current model the active one. Embeddings
+// stored from other models will no longer be comparable; re-import them.
+func (service *ModelService) Activate(ctx context.Context) (*store.Model, error) {
+	info, err := service.sidecarInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.activate(ctx, info)
+}
+
+func (service *ModelService) Status(ctx context.Context) (*ModelStatus, error) {
+	active, err := service.modelStore.FetchActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &ModelStatus{ActiveModel: active}
+	if service.aiClient != nil {
+		status.Sidecar = service.aiClient.Info()
+	}
+	return status, nil
+}
+
+func (service *ModelService) sidecarInfo(ctx context.Context) (*ai.ModelInfo, error) {
+	if service.aiClient == nil {
+		return nil, fmt.Errorf("AIEndpoint is required")
+	}
+	if info := service.aiClient.Info(); info != nil {
+		return info, nil
+	}
+	info, err := service.aiClient.Handshake(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("sidecar handshake: %w", err)
+	}
+	return info, nil
+}
+
+func (service *ModelService) activate(ctx context.Context, info *ai.ModelInfo) (*store.Model, error) {
+	model := &store.Model{
+		Name:          info.Name,
+		Version:       info.Version,
+		Dim:           info.Dim,
+		Normalization: info.Normalization,
+		IsActive:      true,
+	}
+	id, err := service.modelStore.Activate(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	model.ID = id
+	return model, nil
+}
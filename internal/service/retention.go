@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/app"
+	"github.com/face-match/internal/logging"
+	"github.com/face-match/internal/store"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The longest time between two retention cleanups. Shorter TTLs are
+// cleaned up as often as they expire.
+const maxRetentionInterval = 5 * time.Minute
+
+var retentionLogger = logging.For("retention")
+
+// RetentionService enforces the query retention policy on what outlives a
+// request: search jobs and their images, and cached query embeddings.
+type RetentionService struct {
+	config   *app.Config
+	jobStore *store.JobStore
+	cache    *ai.MemoryCache
+}
+
+// NewRetentionService returns a service that also expires the cache, unless
+// it's nil.
+func NewRetentionService(config *app.Config, pool *pgxpool.Pool, cache *ai.MemoryCache) *RetentionService {
+	return &RetentionService{
+		config:   config,
+		jobStore: store.NewJobStore(pool),
+		cache:    cache,
+	}
+}
+
+// Cleanup deletes whatever the policy no longer allows to be kept.
+func (service *RetentionService) Cleanup(ctx context.Context) error {
+	jobs, err := service.jobStore.DeleteFinishedBefore(ctx, time.Now().Add(-service.config.QueryRetentionTTL))
+	if err != nil {
+		return fmt.Errorf("service: retention: %w", err)
+	}
+
+	// Normally gone as soon as the job finished
+	var images int64
+	if service.config.QueryRetention != app.RetentionFull {
+		images, err = service.jobStore.DeleteFinishedImages(ctx)
+		if err != nil {
+			return fmt.Errorf("service: retention: %w", err)
+		}
+	}
+
+	var embeddings int
+	if service.cache != nil {
+		embeddings = service.cache.RemoveExpired()
+	}
+
+	if jobs > 0 || images > 0 || embeddings > 0 {
+		retentionLogger.InfoContext(ctx, "Expired query data deleted", "jobs", jobs, "job_images", images, "embeddings", embeddings)
+	}
+	return nil
+}
+
+// Run cleans up regularly until ctx is cancelled.
+func (service *RetentionService) Run(ctx context.Context) {
+	ticker := time.NewTicker(min(service.config.QueryRetentionTTL, maxRetentionInterval))
+	defer ticker.Stop()
+
+	for {
+		if err := service.Cleanup(ctx); err != nil && ctx.Err() == nil {
+			retentionLogger.ErrorContext(ctx, "Retention cleanup failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
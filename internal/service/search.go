@@ -3,21 +3,41 @@ package service
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sort"
 
 	"github.com/face-match/internal/ai"
 	"github.com/face-match/internal/app"
 	"github.com/face-match/internal/store"
+	"github.com/face-match/internal/tracing"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/face-match/internal/service")
+
 type SearchService struct {
 	config        *app.Config
-	categoryStore *store.CategoryStore
-	imageStore    *store.ImageStore
+	aiClient      *ai.Client
+	categoryStore categoryLister
+	imageStore    imageSearcher
 	personStore   *store.PersonStore
 }
 
+// categoryLister and imageSearcher are what searches need of the category
+// and image stores.
+type categoryLister interface {
+	List(ctx context.Context) ([]store.Category, error)
+}
+
+type imageSearcher interface {
+	FetchById(ctx context.Context, imageID int64) (*store.Image, error)
+	FetchPersonCentroid(ctx context.Context, personID int64) ([]float32, error)
+	Search(ctx context.Context, categoryIDs []int64, embedding []float32, excludePersonID int64) ([]store.Image, error)
+}
+
 type SearchResult struct {
 	ID                int64
 	CategoryID        int64
@@ -27,24 +47,115 @@ type SearchResult struct {
 	SimilarityScore   float32
 }
 
-func NewSearchService(config *app.Config, pool *pgxpool.Pool) *SearchService {
+func NewSearchService(config *app.Config, pool *pgxpool.Pool, aiClient *ai.Client) *SearchService {
 	return &SearchService{
 		config:        config,
+		aiClient:      aiClient,
 		categoryStore: store.NewCategoryStore(pool),
 		imageStore:    store.NewImageStore(pool),
 		personStore:   store.NewPersonStore(pool),
 	}
 }
 
+// Search finds look-alikes of the largest face in the image within the
+// categories. Nothing matches when none are given; callers wanting every
+// category resolve them with DefaultCategoryIDs first.
 func (s *SearchService) Search(ctx context.Context, categoryIDs []int64, imageBytes []byte) ([]SearchResult, error) {
-	embedding, err := ai.FetchEmbedding(ctx, s.config.AIEndpoint, imageBytes)
+	ctx, span := tracer.Start(ctx, "SearchService.Search", trace.WithAttributes(attribute.Int("image.bytes", len(imageBytes))))
+	results, err := s.search(ctx, categoryIDs, imageBytes)
+	tracing.End(span, err)
+	return results, err
+}
+
+func (s *SearchService) search(ctx context.Context, categoryIDs []int64, imageBytes []byte) ([]SearchResult, error) {
+	thresholds, err := s.queryThresholds(ctx, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := decodeImage(imageBytes)
+	if err != nil {
+		return nil, err
+	}
+	embedding, err := fetchEmbedding(ctx, s.aiClient, decoded, imageBytes, thresholds)
+	if err != nil {
+		return nil, fmt.Errorf("fetch embedding: %w", err)
+	}
+	return s.searchEmbedding(ctx, categoryIDs, embedding, 0)
+}
+
+// queryThresholds applies the query quality profile of the searched
+// categories. Category names are only looked up when there are overrides.
+func (s *SearchService) queryThresholds(ctx context.Context, categoryIDs []int64) (ai.QualityThresholds, error) {
+	policy := s.config.Quality
+	if len(policy.Categories) == 0 {
+		return policy.Query, nil
+	}
+
+	categories, err := s.categoryStore.List(ctx)
+	if err != nil {
+		return ai.QualityThresholds{}, fmt.Errorf("list categories: %w", err)
+	}
+	var names []string
+	for _, c := range categories {
+		if slices.Contains(categoryIDs, c.ID) {
+			names = append(names, c.DisplayName)
+		}
+	}
+	return policy.QueryFor(names), nil
+}
+
+// SearchByImage finds look-alikes of an already stored image without
+// re-embedding it. The image's own person is excluded from the results.
+// When no categories are given, every category is searched.
+func (s *SearchService) SearchByImage(ctx context.Context, categoryIDs []int64, imageID int64) ([]SearchResult, error) {
+	image, err := s.imageStore.FetchById(ctx, imageID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch image: %w", err)
+	}
+	categoryIDs, err = s.DefaultCategoryIDs(ctx, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	return s.searchEmbedding(ctx, categoryIDs, image.Embedding, image.PersonID)
+}
+
+// SearchByPerson finds look-alikes of a person using the centroid of all of
+// their stored embeddings. The person is excluded from the results.
+// When no categories are given, every category is searched.
+func (s *SearchService) SearchByPerson(ctx context.Context, categoryIDs []int64, personID int64) ([]SearchResult, error) {
+	centroid, err := s.imageStore.FetchPersonCentroid(ctx, personID)
 	if err != nil {
-		return nil, fmt.Errorf("fetch embedding: %s", err)
+		return nil, fmt.Errorf("fetch centroid: %w", err)
 	}
+	categoryIDs, err = s.DefaultCategoryIDs(ctx, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	return s.searchEmbedding(ctx, categoryIDs, centroid, personID)
+}
+
+// DefaultCategoryIDs returns categoryIDs, or every category when it's empty.
+// The v1 and gRPC APIs search every category when none are given; the
+// unversioned /api only does so for searches by image or person.
+func (s *SearchService) DefaultCategoryIDs(ctx context.Context, categoryIDs []int64) ([]int64, error) {
+	if len(categoryIDs) > 0 {
+		return categoryIDs, nil
+	}
+	categories, err := s.categoryStore.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list categories: %w", err)
+	}
+	ids := make([]int64, 0, len(categories))
+	for _, c := range categories {
+		ids = append(ids, c.ID)
+	}
+	return ids, nil
+}
 
-	images, err := s.imageStore.Search(ctx, categoryIDs, embedding)
+func (s *SearchService) searchEmbedding(ctx context.Context, categoryIDs []int64, embedding []float32, excludePersonID int64) ([]SearchResult, error) {
+	images, err := s.imageStore.Search(ctx, categoryIDs, embedding, excludePersonID)
 	if err != nil {
-		return nil, fmt.Errorf("search: %s", err)
+		return nil, fmt.Errorf("search: %w", err)
 	}
 
 	if images == nil || len(images) == 0 {
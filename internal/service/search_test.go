@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/face-match/internal/ai"
+	"github.com/face-match/internal/ai/fake"
+	"github.com/face-match/internal/app"
+	"github.com/face-match/internal/store"
+)
+
+// fakeStores serves three categories and records the categories every
+// search was limited to.
+type fakeStores struct {
+	mu       sync.Mutex
+	lists    int
+	searched [][]int64
+}
+
+func (f *fakeStores) List(ctx context.Context) ([]store.Category, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lists++
+	return []store.Category{{ID: 1, DisplayName: "A"}, {ID: 2, DisplayName: "B"}, {ID: 3, DisplayName: "C"}}, nil
+}
+
+func (f *fakeStores) FetchById(ctx context.Context, imageID int64) (*store.Image, error) {
+	return &store.Image{ID: imageID, PersonID: 10, Embedding: make([]float32, 512)}, nil
+}
+
+func (f *fakeStores) FetchPersonCentroid(ctx context.Context, personID int64) ([]float32, error) {
+	return make([]float32, 512), nil
+}
+
+func (f *fakeStores) Search(ctx context.Context, categoryIDs []int64, embedding []float32, excludePersonID int64) ([]store.Image, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.searched = append(f.searched, slices.Clone(categoryIDs))
+	return nil, nil
+}
+
+// newFakeSearchService searches stores, embedding with a fake sidecar that
+// finds a face in anything but a flat image. The quality checks are off.
+func newFakeSearchService(t *testing.T, stores *fakeStores) *SearchService {
+	t.Helper()
+	sidecar := httptest.NewServer(fake.New(fake.ModeFace, ai.ModelInfo{Name: "fake", Version: "1", Dim: 512, Normalization: "l2"}))
+	t.Cleanup(sidecar.Close)
+	aiClient, err := ai.NewClient(sidecar.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := app.Default()
+	config.Quality = ai.QualityPolicy{}
+	return &SearchService{config: config, aiClient: aiClient, categoryStore: stores, imageStore: stores}
+}
+
+func patternJpeg(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 128, 128))
+	for y := range 128 {
+		for x := range 128 {
+			img.Set(x, y, color.RGBA{R: uint8(x ^ y), G: uint8(2 * x), B: uint8(2 * y), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDefaultCategoryIDs(t *testing.T) {
+	stores := &fakeStores{}
+	s := newFakeSearchService(t, stores)
+
+	ids, err := s.DefaultCategoryIDs(context.Background(), []int64{2})
+	if err != nil || !slices.Equal(ids, []int64{2}) || stores.lists != 0 {
+		t.Errorf("DefaultCategoryIDs([2]) = %v, %v after %d lists, want [2] without listing", ids, err, stores.lists)
+	}
+	ids, err = s.DefaultCategoryIDs(context.Background(), nil)
+	if err != nil || !slices.Equal(ids, []int64{1, 2, 3}) {
+		t.Errorf("DefaultCategoryIDs(nil) = %v, %v, want every category", ids, err)
+	}
+}
+
+func TestSearchCategories(t *testing.T) {
+	ctx := context.Background()
+	jpg := patternJpeg(t)
+	tests := []struct {
+		name     string
+		search   func(s *SearchService) error
+		searched [][]int64
+	}{
+		{"upload", func(s *SearchService) error {
+			_, err := s.Search(ctx, []int64{2}, jpg)
+			return err
+		}, [][]int64{{2}}},
+		// As the unversioned /api always has; the v1 and gRPC APIs call
+		// DefaultCategoryIDs first
+		{"upload without categories", func(s *SearchService) error {
+			_, err := s.Search(ctx, nil, jpg)
+			return err
+		}, [][]int64{nil}},
+		{"batch", func(s *SearchService) error {
+			results := s.SearchBatch(ctx, []int64{1, 3}, []BatchImage{{Name: "a", Bytes: jpg}, {Name: "b", Bytes: jpg}}, 2)
+			for name, result := range results {
+				if result.Error != "" {
+					t.Errorf("%s failed: %s", name, result.Error)
+				}
+			}
+			return nil
+		}, [][]int64{{1, 3}, {1, 3}}},
+		{"by image", func(s *SearchService) error {
+			_, err := s.SearchByImage(ctx, nil, 7)
+			return err
+		}, [][]int64{{1, 2, 3}}},
+		{"by person", func(s *SearchService) error {
+			_, err := s.SearchByPerson(ctx, []int64{3}, 10)
+			return err
+		}, [][]int64{{3}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stores := &fakeStores{}
+			if err := test.search(newFakeSearchService(t, stores)); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.EqualFunc(stores.searched, test.searched, slices.Equal) {
+				t.Errorf("searched categories %v, want %v", stores.searched, test.searched)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/jpeg"
+	"time"
+
+	"github.com/face-match/internal/video"
+)
+
+const (
+	// Matches below this similarity are not counted as an appearance.
+	videoMatchThreshold = 0.4
+
+	// How many sampled frames a person may be missing from before their
+	// appearance is split in two.
+	videoMaxGapFrames = 1
+)
+
+type VideoFrameResult struct {
+	Index   int
+	Seconds float64
+	Results []SearchResult
+	Error   string
+}
+
+// VideoAppearance is a stretch of the clip where the best match in every
+// sampled frame was the same person.
+type VideoAppearance struct {
+	PersonID          int64
+	CategoryID        int64
+	DisplayName       string
+	DisambiguationTag string
+	StartSeconds      float64
+	EndSeconds        float64
+	Frames            int
+	BestScore         float32
+}
+
+type VideoTimeline struct {
+	Frames      []VideoFrameResult
+	Appearances []VideoAppearance
+}
+
+// SearchVideo samples a frame every interval, searches each for the largest
+// face, and then joins consecutive frames matching the same person into a
+// timeline of appearances.
+func (s *SearchService) SearchVideo(ctx context.Context, categoryIDs []int64, extractor video.Extractor, data []byte, interval time.Duration, concurrency int) (*VideoTimeline, error) {
+	frames, err := extractor.Extract(ctx, data, interval)
+	if err != nil {
+		return nil, fmt.Errorf("extract frames: %w", err)
+	}
+
+	images := make([]BatchImage, 0, len(frames))
+	for _, frame := range frames {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, frame.Image, &jpeg.Options{Quality: 95}); err != nil {
+			return nil, fmt.Errorf("encode frame: %w", err)
+		}
+		images = append(images, BatchImage{Name: frameName(frame.Index), Bytes: buf.Bytes()})
+	}
+
+	batch := s.SearchBatch(ctx, categoryIDs, images, concurrency)
+
+	timeline := &VideoTimeline{
+		Frames:      make([]VideoFrameResult, 0, len(frames)),
+		Appearances: []VideoAppearance{},
+	}
+	for _, frame := range frames {
+		result := batch[frameName(frame.Index)]
+		timeline.Frames = append(timeline.Frames, VideoFrameResult{
+			Index:   frame.Index,
+			Seconds: frame.Time.Seconds(),
+			Results: result.Results,
+			Error:   result.Error,
+		})
+	}
+	timeline.Appearances = trackAppearances(timeline.Frames, interval)
+
+	return timeline, nil
+}
+
+// trackAppearances follows the best match of every frame. Frames without a
+// confident match are treated as gaps; short gaps don't end an appearance.
+func trackAppearances(frames []VideoFrameResult, interval time.Duration) []VideoAppearance {
+	maxGap := interval.Seconds() * (videoMaxGapFrames + 1)
+
+	appearances := []VideoAppearance{}
+	open := make(map[int64]int) // Person id to index in appearances
+	for _, frame := range frames {
+		if len(frame.Results) == 0 || frame.Results[0].SimilarityScore < videoMatchThreshold {
+			continue
+		}
+		best := frame.Results[0]
+
+		if i, ok := open[best.PersonID]; ok && frame.Seconds-appearances[i].EndSeconds <= maxGap {
+			a := &appearances[i]
+			a.EndSeconds = frame.Seconds
+			a.Frames++
+			if best.SimilarityScore > a.BestScore {
+				a.BestScore = best.SimilarityScore
+			}
+			continue
+		}
+
+		open[best.PersonID] = len(appearances)
+		appearances = append(appearances, VideoAppearance{
+			PersonID:          best.PersonID,
+			CategoryID:        best.CategoryID,
+			DisplayName:       best.DisplayName,
+			DisambiguationTag: best.DisambiguationTag,
+			StartSeconds:      frame.Seconds,
+			EndSeconds:        frame.Seconds,
+			Frames:            1,
+			BestScore:         best.SimilarityScore,
+		})
+	}
+	return appearances
+}
+
+func frameName(index int) string {
+	return fmt.Sprintf("frame-%05d.jpg", index)
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackAppearances(t *testing.T) {
+	// frame builds a sampled frame whose best match is person, or which has
+	// no match when person is 0
+	frame := func(seconds float64, person int64, score float32) VideoFrameResult {
+		f := VideoFrameResult{Seconds: seconds}
+		if person != 0 {
+			f.Results = []SearchResult{{PersonID: person, SimilarityScore: score}}
+		}
+		return f
+	}
+	type span struct {
+		person     int64
+		start, end float64
+		frames     int
+		best       float32
+	}
+
+	tests := []struct {
+		name   string
+		frames []VideoFrameResult
+		want   []span
+	}{
+		{"none", nil, nil},
+		{"consecutive", []VideoFrameResult{frame(0, 1, 0.5), frame(1, 1, 0.9), frame(2, 1, 0.6)}, []span{{1, 0, 2, 3, 0.9}}},
+		{"short gap", []VideoFrameResult{frame(0, 1, 0.5), frame(1, 0, 0), frame(2, 1, 0.5)}, []span{{1, 0, 2, 2, 0.5}}},
+		{"long gap", []VideoFrameResult{frame(0, 1, 0.5), frame(1, 0, 0), frame(2, 0, 0), frame(3, 1, 0.5)}, []span{{1, 0, 0, 1, 0.5}, {1, 3, 3, 1, 0.5}}},
+		{"weak match is a gap", []VideoFrameResult{frame(0, 1, 0.5), frame(1, 1, 0.2), frame(2, 1, 0.2), frame(3, 1, 0.5)}, []span{{1, 0, 0, 1, 0.5}, {1, 3, 3, 1, 0.5}}},
+		{"interleaved", []VideoFrameResult{frame(0, 1, 0.5), frame(1, 2, 0.5), frame(2, 1, 0.5)}, []span{{1, 0, 2, 2, 0.5}, {2, 1, 1, 1, 0.5}}},
+	}
+	for _, test := range tests {
+		got := trackAppearances(test.frames, time.Second)
+		if got == nil {
+			t.Errorf("%s: got nil, want an empty list", test.name)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s: got %d appearances, want %d: %+v", test.name, len(got), len(test.want), got)
+			continue
+		}
+		for i, want := range test.want {
+			a := got[i]
+			if a.PersonID != want.person || a.StartSeconds != want.start || a.EndSeconds != want.end || a.Frames != want.frames || a.BestScore != want.best {
+				t.Errorf("%s: appearance %d = %+v, want %+v", test.name, i, a, want)
+			}
+		}
+	}
+}
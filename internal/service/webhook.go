@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var errForbiddenAddress = errors.New("address not allowed")
+
+// Networks webhooks may not reach on top of the loopback, private,
+// link-local, multicast and unspecified ones the netip package knows.
+var forbiddenNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64, which embeds IPv4 addresses
+	netip.MustParsePrefix("2002::/16"),    // 6to4, likewise
+}
+
+// webhookGuard keeps webhooks from reaching the server's own networks, which
+// would let anyone submitting a job make requests on the server's behalf.
+// Addresses in allowed are exempt.
+type webhookGuard struct {
+	allowed []netip.Prefix
+}
+
+func (guard webhookGuard) checkAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	for _, prefix := range guard.allowed {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return fmt.Errorf("%w: %s", errForbiddenAddress, addr)
+	}
+	for _, prefix := range forbiddenNetworks {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: %s", errForbiddenAddress, addr)
+		}
+	}
+	return nil
+}
+
+// checkUrl refuses a webhook URL that isn't http(s), or whose host only
+// resolves to forbidden addresses. The addresses are checked again when
+// connecting, as DNS may answer differently by then.
+func (guard webhookGuard) checkUrl(ctx context.Context, webhookUrl string) error {
+	u, err := url.Parse(webhookUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidWebhookUrl, webhookUrl)
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: %q: %w", ErrInvalidWebhookUrl, webhookUrl, err)
+	}
+	for _, addr := range addrs {
+		if err := guard.checkAddr(addr); err != nil {
+			return fmt.Errorf("%w: %q: %w", ErrInvalidWebhookUrl, webhookUrl, err)
+		}
+	}
+	return nil
+}
+
+// control runs right before each connection is made, on the address that
+// was actually resolved.
+func (guard webhookGuard) control(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	return guard.checkAddr(addr)
+}
+
+// client returns an HTTP client that only connects to allowed addresses. It
+// uses no proxy, which would connect on its behalf, and doesn't follow
+// redirects.
+func (guard webhookGuard) client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: guard.control}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pgvector/pgvector-go"
+)
+
+// Canary is a reference image whose embedding is re-checked to detect the
+// sidecar's output drifting.
+type Canary struct {
+	ID         int64
+	Name       string
+	ImageBytes []byte
+	Embedding  []float32
+	ModelID    int64
+	CreatedAt  time.Time
+}
+
+type CanaryCheck struct {
+	CanaryID int64
+	Drift    *float64
+	Error    string
+}
+
+type CanaryStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewCanaryStore(pool *pgxpool.Pool) *CanaryStore {
+	return &CanaryStore{pool: pool}
+}
+
+// Upsert registers the canary, replacing any canary with the same name.
+func (store *CanaryStore) Upsert(ctx context.Context, canary *Canary) (int64, error) {
+	var id int64
+	err := store.pool.QueryRow(ctx, `
+		INSERT INTO canaries (name, image_bytes, embedding, model_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name)
+		DO UPDATE SET image_bytes = excluded.image_bytes, embedding = excluded.embedding,
+		              model_id = excluded.model_id, created_at = now()
+		RETURNING id
+	`, canary.Name, canary.ImageBytes, pgvector.NewVector(canary.Embedding), canary.ModelID).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("store: canary upsert: %w", err)
+	}
+	return id, nil
+}
+
+// List returns all canaries, including their images and embeddings.
+func (store *CanaryStore) List(ctx context.Context) ([]Canary, error) {
+	rows, err := store.pool.Query(ctx, `
+		SELECT id, name, image_bytes, embedding, model_id, created_at
+		FROM canaries
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("store: canaries list: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Canary
+	for rows.Next() {
+		var c Canary
+		var vec pgvector.Vector
+		if err := rows.Scan(&c.ID, &c.Name, &c.ImageBytes, &vec, &c.ModelID, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("store: canaries scan: %w", err)
+		}
+		c.Embedding = vec.Slice()
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: canaries rows: %w", err)
+	}
+	return out, nil
+}
+
+// Delete removes the canary and its check history.
+func (store *CanaryStore) Delete(ctx context.Context, name string) error {
+	tag, err := store.pool.Exec(ctx, `DELETE FROM canaries WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("store: canary delete: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("store: canary delete: no canary named %q", name)
+	}
+	return nil
+}
+
+func (store *CanaryStore) InsertCheck(ctx context.Context, check *CanaryCheck) error {
+	_, err := store.pool.Exec(ctx, `
+		INSERT INTO canary_checks (canary_id, drift, error)
+		VALUES ($1, $2, $3)
+	`, check.CanaryID, check.Drift, check.Error)
+	if err != nil {
+		return fmt.Errorf("store: canary check insert: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pgvector/pgvector-go"
+)
+
+type CachedEmbedding struct {
+	ContentHash []byte
+	Model       string
+	Embedding   []float32
+	BBox        []float64
+	DetScore    float64
+}
+
+type EmbeddingCacheStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewEmbeddingCacheStore(pool *pgxpool.Pool) *EmbeddingCacheStore {
+	return &EmbeddingCacheStore{pool: pool}
+}
+
+// Fetch returns the cached embedding, or nil when there is none.
+func (store *EmbeddingCacheStore) Fetch(ctx context.Context, contentHash []byte, model string) (*CachedEmbedding, error) {
+	cached := CachedEmbedding{ContentHash: contentHash, Model: model}
+	var vec pgvector.Vector
+	err := store.pool.QueryRow(ctx, `
+		SELECT embedding, bbox, det_score
+		FROM embedding_cache
+		WHERE content_hash = $1 AND model = $2
+	`, contentHash, model).Scan(&vec, &cached.BBox, &cached.DetScore)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: embedding cache fetch: %w", err)
+	}
+	cached.Embedding = vec.Slice()
+	return &cached, nil
+}
+
+func (store *EmbeddingCacheStore) Upsert(ctx context.Context, cached *CachedEmbedding) error {
+	_, err := store.pool.Exec(ctx, `
+		INSERT INTO embedding_cache (content_hash, model, embedding, bbox, det_score)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (content_hash, model)
+		DO UPDATE SET embedding = excluded.embedding, bbox = excluded.bbox, det_score = excluded.det_score
+	`, cached.ContentHash, cached.Model, pgvector.NewVector(cached.Embedding), cached.BBox, cached.DetScore)
+	if err != nil {
+		return fmt.Errorf("store: embedding cache upsert: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// EmbeddingIndex is the HNSW index searches rely on.
+const EmbeddingIndex = "images_embedding_idx"
+
+// LatestMigration returns the version of the newest migration this build
+// ships with, taken from the numeric prefix of its file name.
+func LatestMigration() (int64, error) {
+	entries, err := fs.ReadDir(migrations, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("store: read migrations: %w", err)
+	}
+	var latest int64
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("store: migration %s: %w", entry.Name(), err)
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
+
+type HealthStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewHealthStore(pool *pgxpool.Pool) *HealthStore {
+	return &HealthStore{pool: pool}
+}
+
+func (store *HealthStore) Ping(ctx context.Context) error {
+	if err := store.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("store: ping: %w", err)
+	}
+	return nil
+}
+
+// MigrationVersion returns the newest migration goose has applied.
+func (store *HealthStore) MigrationVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := store.pool.QueryRow(ctx, `
+		SELECT COALESCE(max(version_id), 0)
+		FROM goose_db_version
+		WHERE is_applied
+	`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("store: migration version: %w", err)
+	}
+	return version, nil
+}
+
+// IndexValid reports whether the index exists and is usable; an index whose
+// concurrent build failed exists but is invalid.
+func (store *HealthStore) IndexValid(ctx context.Context, name string) (bool, error) {
+	var valid bool
+	err := store.pool.QueryRow(ctx, `
+		SELECT COALESCE(bool_and(i.indisvalid), false)
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		WHERE c.relname = $1 AND pg_catalog.pg_table_is_visible(c.oid)
+	`, name).Scan(&valid)
+	if err != nil {
+		return false, fmt.Errorf("store: index %s: %w", name, err)
+	}
+	return valid, nil
+}
@@ -3,17 +3,27 @@ package store
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/face-match/internal/metrics"
+	"github.com/face-match/internal/tracing"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pgvector/pgvector-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/face-match/internal/store")
+
 type Image struct {
 	ID         int64
 	CategoryID int64
 	PersonID   int64
 	ImageHash  int64
 	Embedding  []float32
+	ModelID    int64 // 0 when unknown
 
 	// Returned from reading but not used in writing
 	DisplayName       string
@@ -43,14 +53,56 @@ func (store *ImageStore) Insert(ctx context.Context, image *Image) (int64, error
 	vec := pgvector.NewVector(image.Embedding)
 	var id int64
 	err := store.pool.QueryRow(ctx, `
-		INSERT INTO images (category_id, person_id, image_hash, embedding)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO images (category_id, person_id, image_hash, embedding, model_id)
+		VALUES ($1, $2, $3, $4, NULLIF($5::bigint, 0))
 		RETURNING id
-	`, image.CategoryID, image.PersonID, image.ImageHash, vec).Scan(&id)
+	`, image.CategoryID, image.PersonID, image.ImageHash, vec, image.ModelID).Scan(&id)
 	return id, err
 }
 
-func (store *ImageStore) Search(ctx context.Context, categoryIDs []int64, embedding []float32) ([]Image, error) {
+func (store *ImageStore) FetchById(ctx context.Context, imageID int64) (*Image, error) {
+	var image Image
+	var vec pgvector.Vector
+	err := store.pool.QueryRow(ctx, `
+		SELECT id, category_id, person_id, image_hash, embedding
+		FROM images
+		WHERE id = $1
+	`, imageID).Scan(&image.ID, &image.CategoryID, &image.PersonID, &image.ImageHash, &vec)
+	if err != nil {
+		return nil, fmt.Errorf("store: image fetch: %w", err)
+	}
+	image.Embedding = vec.Slice()
+	return &image, nil
+}
+
+// FetchPersonCentroid returns the mean of all embeddings stored for a person.
+// The result is not normalized, which is fine for cosine distance.
+func (store *ImageStore) FetchPersonCentroid(ctx context.Context, personID int64) ([]float32, error) {
+	var vec *pgvector.Vector
+	err := store.pool.QueryRow(ctx, `
+		SELECT avg(embedding)
+		FROM images
+		WHERE person_id = $1
+	`, personID).Scan(&vec)
+	if err != nil {
+		return nil, fmt.Errorf("store: person centroid: %w", err)
+	}
+	if vec == nil {
+		return nil, fmt.Errorf("store: person centroid: %w", pgx.ErrNoRows)
+	}
+	return vec.Slice(), nil
+}
+
+// Search returns the images closest to the embedding. Images belonging to
+// excludePersonID are skipped; pass 0 to include everyone.
+func (store *ImageStore) Search(ctx context.Context, categoryIDs []int64, embedding []float32, excludePersonID int64) ([]Image, error) {
+	ctx, span := tracer.Start(ctx, "ImageStore.Search", trace.WithAttributes(attribute.Int("categories", len(categoryIDs))))
+	images, err := store.search(ctx, categoryIDs, embedding, excludePersonID)
+	tracing.End(span, err)
+	return images, err
+}
+
+func (store *ImageStore) search(ctx context.Context, categoryIDs []int64, embedding []float32, excludePersonID int64) ([]Image, error) {
 	if len(categoryIDs) == 0 {
 		return nil, nil
 	}
@@ -60,11 +112,14 @@ func (store *ImageStore) Search(ctx context.Context, categoryIDs []int64, embedd
 			   i.embedding <=> $2 AS cosine_distance
 		FROM images i
 		JOIN people p ON p.id = i.person_id
-		WHERE i.category_id = ANY($1)
+		WHERE i.category_id = ANY($1) AND i.person_id <> $3
 		ORDER BY cosine_distance
 		LIMIT 10`
+	start := time.Now()
+	defer func() { metrics.VectorQueryDuration.Observe(time.Since(start).Seconds()) }()
+
 	vec := pgvector.NewVector(embedding)
-	rows, err := store.pool.Query(ctx, query, categoryIDs, vec)
+	rows, err := store.pool.Query(ctx, query, categoryIDs, vec, excludePersonID)
 	if err != nil {
 		return nil, fmt.Errorf("search: images select: %s", err)
 	}
@@ -0,0 +1,234 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+type Job struct {
+	ID            string
+	Status        string
+	CategoryIDs   []int64
+	WebhookUrl    string
+	WebhookStatus string
+	Results       []byte // JSON encoded
+	Error         string
+	ClaimToken    string // Set by Claim; identifies the worker running the job
+	Attempts      int    // How often the job was claimed, this claim included
+	CreatedAt     time.Time
+	StartedAt     *time.Time
+	FinishedAt    *time.Time
+}
+
+type JobImage struct {
+	Name       string
+	ImageBytes []byte
+}
+
+type JobStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewJobStore(pool *pgxpool.Pool) *JobStore {
+	return &JobStore{pool: pool}
+}
+
+func (store *JobStore) Insert(ctx context.Context, job *Job, images []JobImage) error {
+	return WithTransaction(ctx, store.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO search_jobs (id, status, category_ids, webhook_url)
+			VALUES ($1, $2, $3, $4)
+		`, job.ID, JobQueued, job.CategoryIDs, job.WebhookUrl)
+		if err != nil {
+			return fmt.Errorf("store: job insert: %w", err)
+		}
+
+		for _, image := range images {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO search_job_images (job_id, name, image_bytes)
+				VALUES ($1, $2, $3)
+			`, job.ID, image.Name, image.ImageBytes)
+			if err != nil {
+				return fmt.Errorf("store: job image insert: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (store *JobStore) FetchById(ctx context.Context, id string) (*Job, error) {
+	row := store.pool.QueryRow(ctx, `
+		SELECT id, status, category_ids, webhook_url, webhook_status, results, error,
+			   attempts, created_at, started_at, finished_at
+		FROM search_jobs
+		WHERE id = $1
+	`, id)
+	job, err := scanJob(row)
+	if err != nil {
+		return nil, fmt.Errorf("store: job fetch: %w", err)
+	}
+	return job, nil
+}
+
+// Claim marks the oldest queued job as running under claimToken and returns
+// it, or returns nil when there is nothing to do. Running jobs whose
+// heartbeat is older than staleAfter are assumed to belong to a dead worker
+// and are claimed again. Every claim counts as an attempt.
+func (store *JobStore) Claim(ctx context.Context, claimToken string, staleAfter time.Duration) (*Job, error) {
+	row := store.pool.QueryRow(ctx, `
+		UPDATE search_jobs
+		SET status = $1, started_at = now(), heartbeat_at = now(), claim_token = $4,
+			attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM search_jobs
+			WHERE status = $2
+				OR (status = $1 AND coalesce(heartbeat_at, started_at) < now() - make_interval(secs => $3))
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, status, category_ids, webhook_url, webhook_status, results, error,
+			attempts, created_at, started_at, finished_at
+	`, JobRunning, JobQueued, staleAfter.Seconds(), claimToken)
+	job, err := scanJob(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: job claim: %w", err)
+	}
+	job.ClaimToken = claimToken
+	return job, nil
+}
+
+// Heartbeat tells other workers that the job is still being worked on. It
+// returns false when the claim was lost, i.e. another worker took the job
+// over.
+func (store *JobStore) Heartbeat(ctx context.Context, id string, claimToken string) (bool, error) {
+	tag, err := store.pool.Exec(ctx, `
+		UPDATE search_jobs
+		SET heartbeat_at = now()
+		WHERE id = $1 AND status = $2 AND claim_token = $3
+	`, id, JobRunning, claimToken)
+	if err != nil {
+		return false, fmt.Errorf("store: job heartbeat: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (store *JobStore) FetchImages(ctx context.Context, jobID string) ([]JobImage, error) {
+	rows, err := store.pool.Query(ctx, `
+		SELECT name, image_bytes
+		FROM search_job_images
+		WHERE job_id = $1
+		ORDER BY id
+	`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("store: job images: %w", err)
+	}
+	defer rows.Close()
+
+	var out []JobImage
+	for rows.Next() {
+		var image JobImage
+		if err := rows.Scan(&image.Name, &image.ImageBytes); err != nil {
+			return nil, fmt.Errorf("store: job images scan: %w", err)
+		}
+		out = append(out, image)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: job images rows: %w", err)
+	}
+	return out, nil
+}
+
+// Finish records the outcome of a job. A job with an error is marked failed.
+// It returns false, recording nothing, when the claim was lost.
+func (store *JobStore) Finish(ctx context.Context, id string, claimToken string, results []byte, jobErr string) (bool, error) {
+	status := JobDone
+	if jobErr != "" {
+		status = JobFailed
+	}
+	tag, err := store.pool.Exec(ctx, `
+		UPDATE search_jobs
+		SET status = $2, results = $3, error = $4, finished_at = now()
+		WHERE id = $1 AND status = $5 AND claim_token = $6
+	`, id, status, results, jobErr, JobRunning, claimToken)
+	if err != nil {
+		return false, fmt.Errorf("store: job finish: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (store *JobStore) SetWebhookStatus(ctx context.Context, id string, webhookStatus string) error {
+	_, err := store.pool.Exec(ctx, `
+		UPDATE search_jobs
+		SET webhook_status = $2
+		WHERE id = $1
+	`, id, webhookStatus)
+	if err != nil {
+		return fmt.Errorf("store: job webhook status: %w", err)
+	}
+	return nil
+}
+
+func scanJob(row pgx.Row) (*Job, error) {
+	var job Job
+	err := row.Scan(&job.ID, &job.Status, &job.CategoryIDs, &job.WebhookUrl, &job.WebhookStatus, &job.Results,
+		&job.Error, &job.Attempts, &job.CreatedAt, &job.StartedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// DeleteImages drops the uploaded images of a job, keeping the job itself.
+func (store *JobStore) DeleteImages(ctx context.Context, id string) error {
+	_, err := store.pool.Exec(ctx, `
+		DELETE FROM search_job_images
+		WHERE job_id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("store: job delete images: %w", err)
+	}
+	return nil
+}
+
+// DeleteFinishedImages drops the uploaded images of every finished job and
+// returns how many there were.
+func (store *JobStore) DeleteFinishedImages(ctx context.Context) (int64, error) {
+	tag, err := store.pool.Exec(ctx, `
+		DELETE FROM search_job_images i
+		USING search_jobs j
+		WHERE i.job_id = j.id AND j.finished_at IS NOT NULL
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("store: job delete finished images: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteFinishedBefore deletes the jobs, and their images, that finished
+// before cutoff and returns how many there were.
+func (store *JobStore) DeleteFinishedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := store.pool.Exec(ctx, `
+		DELETE FROM search_jobs
+		WHERE finished_at < $1
+	`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("store: job delete finished: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,24 @@
+-- +goose Up
+
+CREATE TABLE search_jobs (
+    id TEXT PRIMARY KEY, -- random, so ids can't be guessed
+    status TEXT NOT NULL DEFAULT 'queued', -- queued, running, done, failed
+    category_ids BIGINT[] NOT NULL,
+    webhook_url TEXT NOT NULL DEFAULT '',
+    webhook_status TEXT NOT NULL DEFAULT '',
+    results JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE TABLE search_job_images (
+    id BIGSERIAL PRIMARY KEY,
+    job_id TEXT NOT NULL REFERENCES search_jobs(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    image_bytes BYTEA NOT NULL
+);
+
+CREATE INDEX search_jobs_status_idx ON search_jobs(status, created_at);
+CREATE INDEX search_job_images_job_id_idx ON search_job_images(job_id);
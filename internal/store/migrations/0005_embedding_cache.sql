@@ -0,0 +1,11 @@
+-- +goose Up
+
+CREATE TABLE embedding_cache (
+    content_hash BYTEA NOT NULL, -- sha256 of the image bytes sent to the sidecar
+    model TEXT NOT NULL DEFAULT '',
+    embedding vector(512) NOT NULL,
+    bbox DOUBLE PRECISION[] NOT NULL,
+    det_score DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (content_hash, model)
+);
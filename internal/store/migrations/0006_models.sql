@@ -0,0 +1,17 @@
+-- +goose Up
+
+CREATE TABLE models (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    dim INT NOT NULL,
+    normalization TEXT NOT NULL,
+    is_active BOOL NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE(name, version)
+);
+
+-- At most one model is active
+CREATE UNIQUE INDEX models_is_active_idx ON models(is_active) WHERE is_active;
+
+ALTER TABLE images ADD COLUMN model_id BIGINT REFERENCES models(id); -- null for images from before models were recorded
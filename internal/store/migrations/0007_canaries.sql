@@ -0,0 +1,20 @@
+-- +goose Up
+
+CREATE TABLE canaries (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    image_bytes BYTEA NOT NULL, -- exactly what was sent to the sidecar
+    embedding vector(512) NOT NULL,
+    model_id BIGINT NOT NULL REFERENCES models(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE canary_checks (
+    id BIGSERIAL PRIMARY KEY,
+    canary_id BIGINT NOT NULL REFERENCES canaries(id) ON DELETE CASCADE,
+    drift DOUBLE PRECISION, -- cosine distance; null when the image couldn't be embedded
+    error TEXT NOT NULL DEFAULT '',
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX canary_checks_canary_id_idx ON canary_checks(canary_id, checked_at);
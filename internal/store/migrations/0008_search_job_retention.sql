@@ -0,0 +1,4 @@
+-- +goose Up
+
+-- Finished jobs are deleted once they're older than the retention TTL
+CREATE INDEX search_jobs_finished_at_idx ON search_jobs(finished_at) WHERE finished_at IS NOT NULL;
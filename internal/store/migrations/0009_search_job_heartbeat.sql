@@ -0,0 +1,6 @@
+-- +goose Up
+
+-- Running jobs are only taken over once their worker stops renewing the
+-- heartbeat, and only the worker holding the claim can finish the job.
+ALTER TABLE search_jobs ADD COLUMN heartbeat_at TIMESTAMPTZ;
+ALTER TABLE search_jobs ADD COLUMN claim_token TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+-- +goose Up
+
+-- Counts the claims of a job, so that one whose workers keep dying is
+-- failed instead of being claimed forever.
+ALTER TABLE search_jobs ADD COLUMN attempts INT NOT NULL DEFAULT 0;
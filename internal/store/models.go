@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EmbeddingDim is the size of the images.embedding vector column.
+const EmbeddingDim = 512
+
+type Model struct {
+	ID            int64
+	Name          string
+	Version       string
+	Dim           int
+	Normalization string
+	IsActive      bool
+	CreatedAt     time.Time
+}
+
+type ModelStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewModelStore(pool *pgxpool.Pool) *ModelStore {
+	return &ModelStore{pool: pool}
+}
+
+// FetchActive returns the model that stored embeddings come from, or nil when
+// none has been recorded yet.
+func (store *ModelStore) FetchActive(ctx context.Context) (*Model, error) {
+	var m Model
+	err := store.pool.QueryRow(ctx, `
+		SELECT id, name, version, dim, normalization, is_active, created_at
+		FROM models
+		WHERE is_active
+	`).Scan(&m.ID, &m.Name, &m.Version, &m.Dim, &m.Normalization, &m.IsActive, &m.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: model fetch active: %w", err)
+	}
+	return &m, nil
+}
+
+func (store *ModelStore) List(ctx context.Context) ([]Model, error) {
+	rows, err := store.pool.Query(ctx, `
+		SELECT id, name, version, dim, normalization, is_active, created_at
+		FROM models
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("store: models list: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Model
+	for rows.Next() {
+		var m Model
+		if err := rows.Scan(&m.ID, &m.Name, &m.Version, &m.Dim, &m.Normalization, &m.IsActive, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("store: models scan: %w", err)
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: models rows: %w", err)
+	}
+	return out, nil
+}
+
+// Activate records the model if needed and makes it the only active one.
+func (store *ModelStore) Activate(ctx context.Context, model *Model) (int64, error) {
+	var id int64
+	err := WithTransaction(ctx, store.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `UPDATE models SET is_active = false WHERE is_active`); err != nil {
+			return fmt.Errorf("store: model deactivate: %w", err)
+		}
+		err := tx.QueryRow(ctx, `
+			INSERT INTO models (name, version, dim, normalization, is_active)
+			VALUES ($1, $2, $3, $4, true)
+			ON CONFLICT (name, version)
+			DO UPDATE SET is_active = true
+			RETURNING id
+		`, model.Name, model.Version, model.Dim, model.Normalization).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("store: model activate: %w", err)
+		}
+		return nil
+	})
+	return id, err
+}
@@ -48,7 +48,7 @@ func (store *PersonStore) Upsert(ctx context.Context, person *Person) (int64, er
 
 func (store *PersonStore) Search(ctx context.Context, query string) ([]Person, error) {
 	rows, err := store.pool.Query(ctx, `
-		SELECT p.id, c.display_name category, p.display_name, p.disambiguation_tag, p.is_hidden
+		SELECT p.id, p.category_id, c.display_name category, p.display_name, p.disambiguation_tag, p.is_hidden
 		FROM people p
 		LEFT JOIN categories c ON p.category_id = c.id
 		WHERE p.display_name LIKE '%' || $1 || '%'
@@ -62,7 +62,7 @@ func (store *PersonStore) Search(ctx context.Context, query string) ([]Person, e
 	out := make([]Person, 0, 16)
 	for rows.Next() {
 		var p Person
-		if err := rows.Scan(&p.ID, &p.Category, &p.DisplayName, &p.DisambiguationTag, &p.IsHidden); err != nil {
+		if err := rows.Scan(&p.ID, &p.CategoryId, &p.Category, &p.DisplayName, &p.DisambiguationTag, &p.IsHidden); err != nil {
 			return nil, fmt.Errorf("store: person scan: %w", err)
 		}
 		out = append(out, p)
@@ -0,0 +1,69 @@
+// Package tracing sets up OpenTelemetry tracing. Packages create spans with
+// otel.Tracer as usual; until Setup runs they are no-ops.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup installs W3C trace context propagation and, when endpoint is set,
+// a tracer provider exporting over OTLP/HTTP to it. The endpoint is either
+// the collector's base URL or the full URL of its traces path. sampleRatio is the share
+// of new traces recorded; traces started by a caller follow the caller's
+// decision. The returned function flushes and stops the exporter.
+func Setup(ctx context.Context, serviceName string, endpoint string, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: endpoint: %w", err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		// A base URL, as in OTEL_EXPORTER_OTLP_ENDPOINT
+		u.Path = "/v1/traces"
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(u.String()))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: otlp exporter: %w", err)
+	}
+
+	provider := NewProvider(sdktrace.NewBatchSpanProcessor(exporter), serviceName, sampleRatio)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider creates a tracer provider sending spans to the processor. Pass
+// a processor wrapping tracetest.NewInMemoryExporter to inspect spans in
+// process.
+func NewProvider(processor sdktrace.SpanProcessor, serviceName string, sampleRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+}
+
+// End records err, if any, on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,227 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/png"
+	"time"
+
+	"github.com/face-match/internal/imaging"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+const (
+	apngDisposeNone       = 0
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+	apngBlendSource       = 0
+)
+
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+type apngFrame struct {
+	width, height    uint32
+	xOffset, yOffset uint32
+	delay            time.Duration
+	dispose, blend   byte
+	data             [][]byte // Image data, as IDAT payloads
+}
+
+// extractApng decodes an animated PNG by rebuilding every frame as a
+// standalone PNG for image/png, then compositing it onto the canvas.
+func extractApng(ctx context.Context, data []byte, interval time.Duration) ([]Frame, error) {
+	chunks, err := readPngChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var header []byte
+	var shared []pngChunk // Chunks such as PLTE and tRNS that every frame needs
+	var frames []*apngFrame
+	var current *apngFrame
+	animated := false
+	seenIdat := false
+
+	for _, c := range chunks {
+		switch c.kind {
+		case "IHDR":
+			header = c.data
+		case "acTL":
+			animated = true
+		case "fcTL":
+			current, err = parseFctl(c.data)
+			if err != nil {
+				return nil, err
+			}
+			frames = append(frames, current)
+		case "IDAT":
+			seenIdat = true
+			// The default image is only part of the animation when it has an fcTL.
+			if current != nil {
+				current.data = append(current.data, c.data)
+			}
+		case "fdAT":
+			if current == nil || len(c.data) < 4 {
+				return nil, fmt.Errorf("video: apng: unexpected fdAT")
+			}
+			current.data = append(current.data, c.data[4:])
+		case "IEND":
+		default:
+			if !seenIdat {
+				shared = append(shared, c)
+			}
+		}
+	}
+
+	if !animated {
+		return nil, ErrUnsupportedFormat
+	}
+	if len(header) != 13 {
+		return nil, fmt.Errorf("video: apng: missing header")
+	}
+
+	width := binary.BigEndian.Uint32(header[0:4])
+	height := binary.BigEndian.Uint32(header[4:8])
+	if err := imaging.CheckSize(int(width), int(height)); err != nil {
+		return nil, fmt.Errorf("video: apng: %w", err)
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+
+	s := &sampler{interval: interval}
+	var start time.Duration
+	for _, f := range frames {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if len(f.data) == 0 {
+			continue
+		}
+		if err := imaging.CheckSize(int(f.width), int(f.height)); err != nil {
+			return nil, fmt.Errorf("video: apng: frame: %w", err)
+		}
+
+		img, err := png.Decode(bytes.NewReader(buildPng(header, shared, f)))
+		if err != nil {
+			return nil, fmt.Errorf("video: apng: decode frame: %w", err)
+		}
+
+		region := image.Rect(int(f.xOffset), int(f.yOffset), int(f.xOffset+f.width), int(f.yOffset+f.height))
+		var previous *image.RGBA
+		if f.dispose == apngDisposePrevious {
+			previous = imaging.CloneRGBA(canvas)
+		}
+
+		op := draw.Over
+		if f.blend == apngBlendSource {
+			op = draw.Src
+		}
+		draw.Draw(canvas, region, img, img.Bounds().Min, op)
+
+		end := start + f.delay
+		if at, ok := s.wants(start, end); ok {
+			if err := s.reserve(canvas.Bounds()); err != nil {
+				return nil, err
+			}
+			s.add(at, imaging.CloneRGBA(canvas))
+		}
+		start = end
+		if s.full() {
+			break
+		}
+
+		switch f.dispose {
+		case apngDisposeBackground:
+			draw.Draw(canvas, region, image.Transparent, image.Point{}, draw.Src)
+		case apngDisposePrevious:
+			canvas = previous
+		}
+	}
+	return s.frames, nil
+}
+
+func readPngChunks(data []byte) ([]pngChunk, error) {
+	var chunks []pngChunk
+	rest := data[len(pngSignature):]
+	for len(rest) >= 12 {
+		length := binary.BigEndian.Uint32(rest[0:4])
+		if uint64(length)+12 > uint64(len(rest)) {
+			return nil, fmt.Errorf("video: apng: truncated chunk")
+		}
+		chunks = append(chunks, pngChunk{
+			kind: string(rest[4:8]),
+			data: rest[8 : 8+length],
+		})
+		rest = rest[12+length:]
+	}
+	return chunks, nil
+}
+
+func parseFctl(data []byte) (*apngFrame, error) {
+	if len(data) != 26 {
+		return nil, fmt.Errorf("video: apng: invalid fcTL")
+	}
+	delayNum := binary.BigEndian.Uint16(data[20:22])
+	delayDen := binary.BigEndian.Uint16(data[22:24])
+	if delayDen == 0 {
+		delayDen = 100
+	}
+	delay := time.Duration(delayNum) * time.Second / time.Duration(delayDen)
+	if delay <= 0 {
+		delay = 100 * time.Millisecond
+	}
+
+	return &apngFrame{
+		width:   binary.BigEndian.Uint32(data[4:8]),
+		height:  binary.BigEndian.Uint32(data[8:12]),
+		xOffset: binary.BigEndian.Uint32(data[12:16]),
+		yOffset: binary.BigEndian.Uint32(data[16:20]),
+		delay:   delay,
+		dispose: data[24],
+		blend:   data[25],
+	}, nil
+}
+
+// buildPng assembles a plain PNG holding a single APNG frame.
+func buildPng(header []byte, shared []pngChunk, f *apngFrame) []byte {
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+
+	frameHeader := bytes.Clone(header)
+	binary.BigEndian.PutUint32(frameHeader[0:4], f.width)
+	binary.BigEndian.PutUint32(frameHeader[4:8], f.height)
+	writePngChunk(&buf, "IHDR", frameHeader)
+
+	for _, c := range shared {
+		writePngChunk(&buf, c.kind, c.data)
+	}
+	for _, d := range f.data {
+		writePngChunk(&buf, "IDAT", d)
+	}
+	writePngChunk(&buf, "IEND", nil)
+	return buf.Bytes()
+}
+
+func writePngChunk(buf *bytes.Buffer, kind string, data []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	buf.Write(length[:])
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+	buf.WriteString(kind)
+	buf.Write(data)
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	buf.Write(sum[:])
+}
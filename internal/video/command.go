@@ -0,0 +1,75 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Upper bound on the MJPEG stream read back from the command. Its frames
+// are checked like those of the native decoders.
+const maxCommandOutput = 256 << 20
+
+// CommandExtractor hands the clip to ffmpeg (or a compatible command), which
+// can read any container or codec it was built with. ffmpeg does the
+// sampling and writes the frames back as an MJPEG stream.
+type CommandExtractor struct {
+	Command string
+}
+
+func (e *CommandExtractor) Extract(ctx context.Context, data []byte, interval time.Duration) ([]Frame, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("video: interval must be positive")
+	}
+
+	fps := strconv.FormatFloat(1/interval.Seconds(), 'f', -1, 64)
+	cmd := exec.CommandContext(ctx, e.Command,
+		"-loglevel", "error",
+		"-i", "pipe:0",
+		"-vf", "fps="+fps,
+		"-frames:v", strconv.Itoa(MaxFrames),
+		"-f", "image2pipe",
+		"-vcodec", "mjpeg",
+		"pipe:1",
+	)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("video: %s: %w", e.Command, err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("video: %s: %w", e.Command, err)
+	}
+	output, err := io.ReadAll(io.LimitReader(stdout, maxCommandOutput+1))
+	if err == nil && len(output) > maxCommandOutput {
+		err = fmt.Errorf("%w: more than %d bytes of frames", ErrTooLarge, maxCommandOutput)
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("video: %s: %w", e.Command, err)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("video: %s: %w: %s", e.Command, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	s := &sampler{interval: interval}
+	for i, jpg := range SplitJpegs(output) {
+		img, err := decodeJpegFrame(s, jpg)
+		if err != nil {
+			return nil, err
+		}
+		s.add(time.Duration(i)*interval, img)
+	}
+	if len(s.frames) == 0 {
+		return nil, fmt.Errorf("video: no frames extracted")
+	}
+	return s.frames, nil
+}
@@ -0,0 +1,52 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/gif"
+	"time"
+
+	"github.com/face-match/internal/imaging"
+)
+
+func extractGif(ctx context.Context, data []byte, interval time.Duration) ([]Frame, error) {
+	// Every frame is decoded, not only the sampled ones
+	if err := imaging.CheckGif(data); err != nil {
+		return nil, fmt.Errorf("video: gif: %w", err)
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("video: decode gif: %w", err)
+	}
+
+	s := &sampler{interval: interval}
+	var start time.Duration
+	var sampleErr error
+	err = imaging.CompositeGif(g, func(i int, canvas *image.RGBA) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		end := start + imaging.GifDelay(g.Delay[i])
+		if at, ok := s.wants(start, end); ok {
+			if sampleErr = s.reserve(canvas.Bounds()); sampleErr != nil {
+				return false
+			}
+			s.add(at, imaging.CloneRGBA(canvas))
+		}
+		start = end
+		return !s.full()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sampleErr != nil {
+		return nil, sampleErr
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return s.frames, nil
+}
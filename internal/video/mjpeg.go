@@ -0,0 +1,148 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"time"
+
+	"github.com/face-match/internal/imaging"
+)
+
+// extractMjpeg samples a stream of back-to-back JPEG images.
+func extractMjpeg(ctx context.Context, data []byte, interval time.Duration, perFrame time.Duration) ([]Frame, error) {
+	s := &sampler{interval: interval}
+	var start time.Duration
+
+	for _, jpg := range SplitJpegs(data) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		end := start + perFrame
+		if at, ok := s.wants(start, end); ok {
+			img, err := decodeJpegFrame(s, jpg)
+			if err != nil {
+				return nil, err
+			}
+			s.add(at, img)
+		}
+		start = end
+		if s.full() {
+			break
+		}
+	}
+
+	if len(s.frames) == 0 {
+		return nil, fmt.Errorf("video: no frames found")
+	}
+	return s.frames, nil
+}
+
+// decodeJpegFrame decodes one frame of a stream, checking its size and
+// reserving it with the sampler first.
+func decodeJpegFrame(s *sampler, jpg []byte) (image.Image, error) {
+	config, err := jpeg.DecodeConfig(bytes.NewReader(jpg))
+	if err != nil {
+		return nil, fmt.Errorf("video: decode mjpeg frame: %w", err)
+	}
+	if err := imaging.CheckSize(config.Width, config.Height); err != nil {
+		return nil, fmt.Errorf("video: mjpeg frame: %w", err)
+	}
+	if err := s.reserve(image.Rect(0, 0, config.Width, config.Height)); err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(bytes.NewReader(jpg))
+	if err != nil {
+		return nil, fmt.Errorf("video: decode mjpeg frame: %w", err)
+	}
+	return img, nil
+}
+
+// An AVI file starts with "RIFF", its size and "AVI ".
+func isAviMjpeg(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "AVI "
+}
+
+// extractAviMjpeg handles MJPEG in an AVI container. Instead of walking the
+// RIFF structure, it reads the frame rate from the main header and then
+// finds the JPEG frames themselves. Other AVI codecs yield no frames and are
+// reported as unsupported.
+func extractAviMjpeg(ctx context.Context, data []byte, interval time.Duration) ([]Frame, error) {
+	perFrame := frameDuration(DefaultFrameRate)
+	if i := bytes.Index(data, []byte("avih")); i >= 0 && i+12 <= len(data) {
+		if micros := binary.LittleEndian.Uint32(data[i+8 : i+12]); micros > 0 {
+			perFrame = time.Duration(micros) * time.Microsecond
+		}
+	}
+
+	start := bytes.Index(data, []byte{0xFF, 0xD8, 0xFF})
+	if start < 0 {
+		return nil, ErrUnsupportedFormat
+	}
+	return extractMjpeg(ctx, data[start:], interval, perFrame)
+}
+
+// SplitJpegs finds the JPEG images in data. Segments are walked by their
+// lengths, so embedded thumbnails don't end a frame early. Anything between
+// images, such as container headers, is skipped.
+func SplitJpegs(data []byte) [][]byte {
+	var out [][]byte
+	for {
+		start := bytes.Index(data, []byte{0xFF, 0xD8, 0xFF})
+		if start < 0 {
+			return out
+		}
+		data = data[start:]
+		end := jpegEnd(data)
+		if end < 0 {
+			return out
+		}
+		out = append(out, data[:end])
+		data = data[end:]
+	}
+}
+
+// jpegEnd returns the offset just past the EOI marker of the JPEG starting at
+// data[0], or -1 when the image is truncated.
+func jpegEnd(data []byte) int {
+	i := 2 // Skip SOI
+	for i+2 <= len(data) {
+		if data[i] != 0xFF {
+			return -1
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF:
+			// Fill byte
+			i++
+			continue
+		case marker == 0xD9:
+			return i + 2
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			i += 2
+			continue
+		}
+
+		if i+4 > len(data) {
+			return -1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		i += 2 + length
+		if marker != 0xDA {
+			continue
+		}
+
+		// Entropy coded data follows the start of scan; it ends at the first
+		// marker that isn't a stuffed byte or a restart marker.
+		for i+1 < len(data) {
+			if data[i] == 0xFF && data[i+1] != 0x00 && (data[i+1] < 0xD0 || data[i+1] > 0xD7) {
+				break
+			}
+			i++
+		}
+	}
+	return -1
+}
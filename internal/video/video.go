@@ -0,0 +1,144 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/face-match/internal/imaging"
+)
+
+const (
+	// Upper bound on sampled frames, so a long clip can't keep the sidecar
+	// busy indefinitely.
+	MaxFrames = 300
+
+	// Upper bound on the pixels of all sampled frames together, which are
+	// kept in memory until they're searched: about 200 frames of 720p
+	// video. Longer clips need a longer interval.
+	MaxSampledPixels = 4 * imaging.MaxPixels
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("video: unsupported format")
+
+	// ErrTooLarge means the sampled frames would exceed MaxSampledPixels.
+	ErrTooLarge = errors.New("video: sampled frames too large")
+)
+
+type Frame struct {
+	Index int           // Position among the sampled frames
+	Time  time.Duration // Offset from the start of the clip
+	Image image.Image
+}
+
+// Extractor samples one frame per interval from a video clip.
+type Extractor interface {
+	Extract(ctx context.Context, data []byte, interval time.Duration) ([]Frame, error)
+}
+
+// NewExtractor returns the native decoders for animated GIF, APNG and MJPEG.
+// When command is set (e.g. "ffmpeg"), it is used for all other formats.
+func NewExtractor(command string) Extractor {
+	native := &NativeExtractor{FrameRate: DefaultFrameRate}
+	if command == "" {
+		return native
+	}
+	return &fallbackExtractor{
+		extractors: []Extractor{native, &CommandExtractor{Command: command}},
+	}
+}
+
+// The frame rate assumed for raw MJPEG streams, which carry no timing.
+const DefaultFrameRate = 25
+
+// NativeExtractor decodes the formats that need nothing beyond the Go
+// standard library.
+type NativeExtractor struct {
+	FrameRate float64 // Used for raw MJPEG streams
+}
+
+func (e *NativeExtractor) Extract(ctx context.Context, data []byte, interval time.Duration) ([]Frame, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("video: interval must be positive")
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return extractGif(ctx, data, interval)
+	case bytes.HasPrefix(data, pngSignature):
+		return extractApng(ctx, data, interval)
+	case isAviMjpeg(data):
+		return extractAviMjpeg(ctx, data, interval)
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return extractMjpeg(ctx, data, interval, frameDuration(e.FrameRate))
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type fallbackExtractor struct {
+	extractors []Extractor
+}
+
+func (e *fallbackExtractor) Extract(ctx context.Context, data []byte, interval time.Duration) ([]Frame, error) {
+	for _, extractor := range e.extractors {
+		frames, err := extractor.Extract(ctx, data, interval)
+		if errors.Is(err, ErrUnsupportedFormat) {
+			continue
+		}
+		return frames, err
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// sampler picks the frame that is on screen at every multiple of interval.
+type sampler struct {
+	interval time.Duration
+	next     time.Duration
+	frames   []Frame
+	pixels   int64 // Of all frames, including those reserved
+}
+
+// wants reports whether the frame shown from start until end should be
+// sampled, and the sample time to record for it. Samples falling inside a
+// long frame collapse into one.
+func (s *sampler) wants(start, end time.Duration) (time.Duration, bool) {
+	if s.full() || s.next < start || s.next >= end {
+		return 0, false
+	}
+	at := s.next
+	for s.next < end {
+		s.next += s.interval
+	}
+	return at, true
+}
+
+// reserve accounts for a frame of the given bounds before it is decoded or
+// copied, refusing it with ErrTooLarge when the frames would together
+// exceed MaxSampledPixels.
+func (s *sampler) reserve(bounds image.Rectangle) error {
+	s.pixels += int64(bounds.Dx()) * int64(bounds.Dy())
+	if s.pixels > MaxSampledPixels {
+		return fmt.Errorf("%w: more than %d pixels", ErrTooLarge, int64(MaxSampledPixels))
+	}
+	return nil
+}
+
+func (s *sampler) add(at time.Duration, img image.Image) {
+	s.frames = append(s.frames, Frame{Index: len(s.frames), Time: at, Image: img})
+}
+
+func (s *sampler) full() bool {
+	return len(s.frames) >= MaxFrames
+}
+
+func frameDuration(frameRate float64) time.Duration {
+	if frameRate <= 0 {
+		frameRate = DefaultFrameRate
+	}
+	return time.Duration(float64(time.Second) / frameRate)
+}
@@ -0,0 +1,338 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/face-match/internal/imaging"
+)
+
+func encodePngChunk(kind string, data []byte) []byte {
+	var buf bytes.Buffer
+	writePngChunk(&buf, kind, data)
+	return buf.Bytes()
+}
+
+// hugeApng declares a canvas far over the limits, and nothing else.
+func hugeApng() []byte {
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint32(header[0:4], 100_000)
+	binary.BigEndian.PutUint32(header[4:8], 100_000)
+	header[8] = 8 // Bit depth
+	header[9] = 6 // RGBA
+
+	data := bytes.Clone(pngSignature)
+	data = append(data, encodePngChunk("IHDR", header)...)
+	data = append(data, encodePngChunk("acTL", make([]byte, 8))...)
+	return append(data, encodePngChunk("IEND", nil)...)
+}
+
+// hugeGif declares a 65535x65535 logical screen, and nothing else.
+func hugeGif() []byte {
+	data := []byte("GIF89a")
+	data = binary.LittleEndian.AppendUint16(data, 0xffff)
+	data = binary.LittleEndian.AppendUint16(data, 0xffff)
+	return append(data, 0, 0, 0, 0x3b)
+}
+
+// gifBomb repeats a blank 7000x7000 frame, which compresses to a few
+// kilobytes, a thousand times.
+func gifBomb(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 7000, 7000), palette.Plan9), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// The Plan 9 palette is a 256 colour global table
+	header := 13 + 3*256
+	bomb := bytes.Clone(data[:header])
+	for range 1000 {
+		bomb = append(bomb, data[header:len(data)-1]...)
+	}
+	return append(bomb, 0x3b)
+}
+
+// hugeMjpeg is two small JPEG frames whose headers claim 60000x60000.
+func hugeMjpeg(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 16, 16)), nil); err != nil {
+		t.Fatal(err)
+	}
+	frame := buf.Bytes()
+	sof := bytes.Index(frame, []byte{0xff, 0xc0})
+	if sof < 0 {
+		t.Fatal("no SOF0 marker")
+	}
+	binary.BigEndian.PutUint16(frame[sof+5:], 60000)
+	binary.BigEndian.PutUint16(frame[sof+7:], 60000)
+	return append(bytes.Clone(frame), frame...)
+}
+
+func TestExtractRefusesHugeFrames(t *testing.T) {
+	tests := map[string][]byte{
+		"apng":     hugeApng(),
+		"gif":      hugeGif(),
+		"gif bomb": gifBomb(t),
+		"mjpeg":    hugeMjpeg(t),
+	}
+	extractor := &NativeExtractor{FrameRate: DefaultFrameRate}
+	for name, data := range tests {
+		_, err := extractor.Extract(context.Background(), data, time.Second)
+		if !errors.Is(err, imaging.ErrTooLarge) {
+			t.Errorf("%s: Extract() = %v, want imaging.ErrTooLarge", name, err)
+		}
+	}
+}
+
+func TestSamplerWants(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		frames   []time.Duration // Durations of consecutive frames
+		want     []time.Duration // Sample times
+	}{
+		{"short frames", time.Second, []time.Duration{400 * time.Millisecond, 400 * time.Millisecond, 400 * time.Millisecond, 400 * time.Millisecond, 400 * time.Millisecond, 400 * time.Millisecond}, []time.Duration{0, time.Second, 2 * time.Second}},
+		{"one frame per sample", time.Second, []time.Duration{time.Second, time.Second, time.Second}, []time.Duration{0, time.Second, 2 * time.Second}},
+		{"long frame", time.Second, []time.Duration{3500 * time.Millisecond, time.Second}, []time.Duration{0, 4 * time.Second}},
+	}
+	for _, test := range tests {
+		s := &sampler{interval: test.interval}
+		var got []time.Duration
+		var start time.Duration
+		for _, d := range test.frames {
+			if at, ok := s.wants(start, start+d); ok {
+				got = append(got, at)
+			}
+			start += d
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: sampled at %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSamplerLimits(t *testing.T) {
+	s := &sampler{interval: time.Second}
+	for i := 0; i < MaxFrames; i++ {
+		if s.full() {
+			t.Fatalf("full after %d frames, want %d", i, MaxFrames)
+		}
+		s.add(time.Duration(i)*time.Second, nil)
+	}
+	if !s.full() {
+		t.Errorf("not full after %d frames", MaxFrames)
+	}
+
+	// Each frame is within the imaging limits, but not all of them together
+	s = &sampler{interval: time.Second}
+	frame := image.Rect(0, 0, 7000, 7000)
+	for i := 0; i < 4; i++ {
+		if err := s.reserve(frame); err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+	}
+	if err := s.reserve(frame); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("reserve() = %v, want ErrTooLarge", err)
+	}
+}
+
+func encodeJpeg(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSplitJpegs(t *testing.T) {
+	a := encodeJpeg(t, 16, 16)
+	b := encodeJpeg(t, 8, 24)
+
+	// An APP1 segment holding a thumbnail, whose EOI doesn't end the frame
+	thumbnail := encodeJpeg(t, 4, 4)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(thumbnail)+2))
+	withThumbnail := slices.Concat(a[:2], app1, thumbnail, a[2:])
+
+	tests := []struct {
+		name string
+		data []byte
+		want [][]byte
+	}{
+		{"back to back", slices.Concat(a, b), [][]byte{a, b}},
+		{"container bytes between", slices.Concat([]byte("RIFF....AVI "), a, []byte("00dc...."), b), [][]byte{a, b}},
+		{"truncated last", slices.Concat(a, b[:len(b)/2]), [][]byte{a}},
+		{"embedded thumbnail", withThumbnail, [][]byte{withThumbnail}},
+		{"none", []byte("not a jpeg"), nil},
+	}
+	for _, test := range tests {
+		got := SplitJpegs(test.data)
+		if len(got) != len(test.want) {
+			t.Errorf("%s: got %d images, want %d", test.name, len(got), len(test.want))
+			continue
+		}
+		for i := range got {
+			if !bytes.Equal(got[i], test.want[i]) {
+				t.Errorf("%s: image %d differs", test.name, i)
+			}
+		}
+	}
+}
+
+// apngFrameSpec is a frame of a test APNG, filled with one colour.
+type apngFrameSpec struct {
+	rect           image.Rectangle
+	colour         color.NRGBA
+	dispose, blend byte
+}
+
+// buildApng encodes an animation whose frames each last a second.
+func buildApng(t *testing.T, width, height int, specs []apngFrameSpec) []byte {
+	t.Helper()
+	data := bytes.Clone(pngSignature)
+	acTL := make([]byte, 8)
+	binary.BigEndian.PutUint32(acTL[0:4], uint32(len(specs)))
+
+	var sequence uint32
+	for i, spec := range specs {
+		img := image.NewNRGBA(image.Rect(0, 0, spec.rect.Dx(), spec.rect.Dy()))
+		for j := 0; j < len(img.Pix); j += 4 {
+			copy(img.Pix[j:], []byte{spec.colour.R, spec.colour.G, spec.colour.B, spec.colour.A})
+		}
+		var encoded bytes.Buffer
+		if err := png.Encode(&encoded, img); err != nil {
+			t.Fatal(err)
+		}
+		chunks, err := readPngChunks(encoded.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i == 0 {
+			header := bytes.Clone(chunks[0].data)
+			binary.BigEndian.PutUint32(header[0:4], uint32(width))
+			binary.BigEndian.PutUint32(header[4:8], uint32(height))
+			data = append(data, encodePngChunk("IHDR", header)...)
+			data = append(data, encodePngChunk("acTL", acTL)...)
+		}
+
+		fcTL := make([]byte, 26)
+		binary.BigEndian.PutUint32(fcTL[0:4], sequence)
+		binary.BigEndian.PutUint32(fcTL[4:8], uint32(spec.rect.Dx()))
+		binary.BigEndian.PutUint32(fcTL[8:12], uint32(spec.rect.Dy()))
+		binary.BigEndian.PutUint32(fcTL[12:16], uint32(spec.rect.Min.X))
+		binary.BigEndian.PutUint32(fcTL[16:20], uint32(spec.rect.Min.Y))
+		binary.BigEndian.PutUint16(fcTL[20:22], 1)
+		binary.BigEndian.PutUint16(fcTL[22:24], 1)
+		fcTL[24], fcTL[25] = spec.dispose, spec.blend
+		data = append(data, encodePngChunk("fcTL", fcTL)...)
+		sequence++
+
+		for _, c := range chunks {
+			if c.kind != "IDAT" {
+				continue
+			}
+			if i == 0 {
+				data = append(data, encodePngChunk("IDAT", c.data)...)
+				continue
+			}
+			fdAT := binary.BigEndian.AppendUint32(nil, sequence)
+			data = append(data, encodePngChunk("fdAT", append(fdAT, c.data...))...)
+			sequence++
+		}
+	}
+	return append(data, encodePngChunk("IEND", nil)...)
+}
+
+func TestExtractApng(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	green := color.NRGBA{G: 255, A: 255}
+	data := buildApng(t, 4, 4, []apngFrameSpec{
+		{image.Rect(0, 0, 4, 4), red, apngDisposeNone, apngBlendSource},
+		{image.Rect(2, 2, 4, 4), blue, apngDisposeBackground, apngBlendSource},
+		{image.Rect(0, 0, 1, 1), green, apngDisposeNone, 1},
+	})
+
+	frames, err := extractApng(context.Background(), data, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, want 3", len(frames))
+	}
+
+	tests := []struct {
+		frame int
+		x, y  int
+		want  color.RGBA
+	}{
+		{0, 3, 3, color.RGBA{R: 255, A: 255}},
+		{1, 3, 3, color.RGBA{B: 255, A: 255}},
+		{1, 0, 0, color.RGBA{R: 255, A: 255}},
+		// The blue frame was disposed to transparency
+		{2, 3, 3, color.RGBA{}},
+		{2, 0, 0, color.RGBA{G: 255, A: 255}},
+		{2, 1, 1, color.RGBA{R: 255, A: 255}},
+	}
+	for _, test := range tests {
+		got := color.RGBAModel.Convert(frames[test.frame].Image.At(test.x, test.y))
+		if got != test.want {
+			t.Errorf("frame %d at (%d, %d) = %v, want %v", test.frame, test.x, test.y, got, test.want)
+		}
+	}
+	for i, frame := range frames {
+		if frame.Index != i || frame.Time != time.Duration(i)*time.Second {
+			t.Errorf("frame %d is #%d at %v", i, frame.Index, frame.Time)
+		}
+	}
+}
+
+// fakeCommand is a command that ignores its arguments and writes output, as
+// ffmpeg would write the frames.
+func fakeCommand(t *testing.T, output []byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	frames := filepath.Join(dir, "frames")
+	if err := os.WriteFile(frames, output, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	command := filepath.Join(dir, "ffmpeg")
+	script := "#!/bin/sh\ncat >/dev/null\ncat '" + frames + "'\n"
+	if err := os.WriteFile(command, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return command
+}
+
+func TestCommandExtractor(t *testing.T) {
+	extractor := &CommandExtractor{Command: fakeCommand(t, slices.Concat(encodeJpeg(t, 16, 16), encodeJpeg(t, 16, 16)))}
+	frames, err := extractor.Extract(context.Background(), []byte("clip"), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 || frames[1].Time != 2*time.Second {
+		t.Errorf("got %d frames, want 2 two seconds apart", len(frames))
+	}
+
+	extractor = &CommandExtractor{Command: fakeCommand(t, hugeMjpeg(t))}
+	if _, err := extractor.Extract(context.Background(), []byte("clip"), time.Second); !errors.Is(err, imaging.ErrTooLarge) {
+		t.Errorf("Extract() = %v, want imaging.ErrTooLarge", err)
+	}
+}
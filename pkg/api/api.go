@@ -0,0 +1,167 @@
+// Package api defines the JSON documents of the server's /api/v1 endpoints.
+// They are kept apart from the server's own types so that those can change
+// without breaking clients; fields are only ever added.
+package api
+
+import (
+	_ "embed"
+	"time"
+)
+
+// OpenAPI is the OpenAPI 3 description of /api/v1, in YAML.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+// Job statuses.
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+type Category struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type CategoryList struct {
+	Categories []Category `json:"categories"`
+}
+
+// Match is a person whose enrolled image resembles the query.
+type Match struct {
+	ImageID           int64   `json:"image_id"`
+	PersonID          int64   `json:"person_id"`
+	CategoryID        int64   `json:"category_id"`
+	DisplayName       string  `json:"display_name"`
+	DisambiguationTag string  `json:"disambiguation_tag,omitempty"`
+	Similarity        float32 `json:"similarity"` // Cosine similarity, higher is closer
+}
+
+type SearchResponse struct {
+	Matches []Match `json:"matches"`
+}
+
+// BatchResult is the outcome for one image of a batch. Error is set instead
+// of Matches when that image couldn't be searched, to the code an error
+// response would have, such as no_face.
+type BatchResult struct {
+	Matches []Match `json:"matches"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// BatchResponse maps every image name to its result. Repeated names get a
+// numeric suffix.
+type BatchResponse struct {
+	Results map[string]BatchResult `json:"results"`
+}
+
+type VideoFrame struct {
+	Index   int     `json:"index"`
+	Seconds float64 `json:"seconds"`
+	Matches []Match `json:"matches"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// Appearance is a stretch of the video where the best match in every sampled
+// frame was the same person.
+type Appearance struct {
+	PersonID          int64   `json:"person_id"`
+	CategoryID        int64   `json:"category_id"`
+	DisplayName       string  `json:"display_name"`
+	DisambiguationTag string  `json:"disambiguation_tag,omitempty"`
+	StartSeconds      float64 `json:"start_seconds"`
+	EndSeconds        float64 `json:"end_seconds"`
+	Frames            int     `json:"frames"`
+	BestSimilarity    float32 `json:"best_similarity"`
+}
+
+type VideoResponse struct {
+	Frames      []VideoFrame `json:"frames"`
+	Appearances []Appearance `json:"appearances"`
+}
+
+// Job is a queued batch search. Results is set once it's done.
+type Job struct {
+	ID         string                 `json:"id"`
+	Status     string                 `json:"status"`
+	CreatedAt  *time.Time             `json:"created_at,omitempty"`
+	StartedAt  *time.Time             `json:"started_at,omitempty"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+	Results    map[string]BatchResult `json:"results,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+type Model struct {
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	Dim           int    `json:"dim"`
+	Normalization string `json:"normalization"`
+}
+
+// Info describes the model the sidecar runs and the one the database's
+// embeddings were made with. Searches only work when they're the same.
+type Info struct {
+	Sidecar     *Model `json:"sidecar"`
+	ActiveModel *Model `json:"active_model"`
+}
+
+type Person struct {
+	ID                int64  `json:"id"`
+	CategoryID        int64  `json:"category_id"`
+	Category          string `json:"category"`
+	DisplayName       string `json:"display_name"`
+	DisambiguationTag string `json:"disambiguation_tag,omitempty"`
+	Hidden            bool   `json:"hidden"`
+}
+
+type PersonList struct {
+	People []Person `json:"people"`
+}
+
+// PersonUpdate is the body of a person PATCH. Fields left out are kept.
+type PersonUpdate struct {
+	Hidden *bool `json:"hidden,omitempty"`
+}
+
+type CanaryResult struct {
+	Name    string  `json:"name"`
+	Drift   float64 `json:"drift"` // Cosine distance from the registered embedding
+	Drifted bool    `json:"drifted"`
+	Error   string  `json:"error,omitempty"`
+}
+
+type CanaryCheck struct {
+	Threshold float64        `json:"threshold"`
+	Results   []CanaryResult `json:"results"`
+}
+
+// ErrorResponse is returned by every failed request. Code is stable and
+// meant for programs; Message can be shown to users.
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes a failure. Images rejected for their quality also
+// carry the check that failed and the full report.
+type ErrorDetail struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	Reason    string         `json:"reason,omitempty"`
+	Value     *float64       `json:"value,omitempty"`
+	Threshold *float64       `json:"threshold,omitempty"`
+	Quality   *QualityReport `json:"quality,omitempty"`
+}
+
+type QualityReport struct {
+	Checks []QualityCheck `json:"checks"`
+}
+
+type QualityCheck struct {
+	Name      string  `json:"name"` // det_score, face_height, face_size or sharpness
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Passed    bool    `json:"passed"`
+}
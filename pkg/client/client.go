@@ -0,0 +1,286 @@
+// Package client calls the server's /api/v1 endpoints.
+//
+//	c, err := client.New("http://localhost:8080")
+//	...
+//	matches, err := c.Search(ctx, photo, "photo.jpg", nil)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/face-match/pkg/api"
+)
+
+// Error is a request the server refused or failed. Detail.Code tells the
+// failures apart, e.g. "no_face" or "low_quality".
+type Error struct {
+	StatusCode int
+	Detail     api.ErrorDetail
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("face-match: %d %s: %s", e.StatusCode, e.Detail.Code, e.Detail.Message)
+}
+
+// Image is one image of a batch. Name identifies its result.
+type Image struct {
+	Name  string
+	Bytes []byte
+}
+
+type Client struct {
+	baseURL *url.URL
+
+	// HTTPClient sends the requests; http.DefaultClient when nil.
+	HTTPClient *http.Client
+
+	// AdminToken is sent with the admin calls.
+	AdminToken string
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "http://localhost:8080".
+func New(baseURL string) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("client: invalid base url %q", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v1/"
+	return &Client{baseURL: u}, nil
+}
+
+func (c *Client) Categories(ctx context.Context) ([]api.Category, error) {
+	var out api.CategoryList
+	if err := c.do(ctx, http.MethodGet, "categories", nil, "", false, &out); err != nil {
+		return nil, err
+	}
+	return out.Categories, nil
+}
+
+func (c *Client) Info(ctx context.Context) (*api.Info, error) {
+	var out api.Info
+	if err := c.do(ctx, http.MethodGet, "info", nil, "", false, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Search searches the largest face in an image. No categoryIDs searches them
+// all.
+func (c *Client) Search(ctx context.Context, image []byte, filename string, categoryIDs []int64) ([]api.Match, error) {
+	return c.search(ctx, categoryIDs, func(form *multipart.Writer) error {
+		return writeFile(form, "image", filename, image)
+	})
+}
+
+// SearchByImage searches with the embedding of a stored image, leaving out
+// its person.
+func (c *Client) SearchByImage(ctx context.Context, imageID int64, categoryIDs []int64) ([]api.Match, error) {
+	return c.search(ctx, categoryIDs, func(form *multipart.Writer) error {
+		return form.WriteField("image_id", strconv.FormatInt(imageID, 10))
+	})
+}
+
+// SearchByPerson searches with the average embedding of a person, leaving
+// them out.
+func (c *Client) SearchByPerson(ctx context.Context, personID int64, categoryIDs []int64) ([]api.Match, error) {
+	return c.search(ctx, categoryIDs, func(form *multipart.Writer) error {
+		return form.WriteField("person_id", strconv.FormatInt(personID, 10))
+	})
+}
+
+func (c *Client) search(ctx context.Context, categoryIDs []int64, fields func(*multipart.Writer) error) ([]api.Match, error) {
+	body, contentType, err := buildForm(categoryIDs, fields)
+	if err != nil {
+		return nil, err
+	}
+	var out api.SearchResponse
+	if err := c.do(ctx, http.MethodPost, "search", body, contentType, false, &out); err != nil {
+		return nil, err
+	}
+	return out.Matches, nil
+}
+
+// SearchBatch searches every image, returning the results by name. A failure
+// for one image is reported in its result.
+func (c *Client) SearchBatch(ctx context.Context, images []Image, categoryIDs []int64) (map[string]api.BatchResult, error) {
+	body, contentType, err := buildForm(categoryIDs, imageFields(images))
+	if err != nil {
+		return nil, err
+	}
+	var out api.BatchResponse
+	if err := c.do(ctx, http.MethodPost, "search/batch", body, contentType, false, &out); err != nil {
+		return nil, err
+	}
+	return out.Results, nil
+}
+
+// SubmitJob queues a batch search and returns the job's id. The finished job
+// is posted to webhookURL, unless it's empty.
+func (c *Client) SubmitJob(ctx context.Context, images []Image, categoryIDs []int64, webhookURL string) (string, error) {
+	fields := imageFields(images)
+	body, contentType, err := buildForm(categoryIDs, func(form *multipart.Writer) error {
+		if webhookURL != "" {
+			if err := form.WriteField("webhook_url", webhookURL); err != nil {
+				return err
+			}
+		}
+		return fields(form)
+	})
+	if err != nil {
+		return "", err
+	}
+	var out api.Job
+	if err := c.do(ctx, http.MethodPost, "jobs", body, contentType, false, &out); err != nil {
+		return "", err
+	}
+	return out.ID, nil
+}
+
+func (c *Client) Job(ctx context.Context, id string) (*api.Job, error) {
+	var out api.Job
+	if err := c.do(ctx, http.MethodGet, "jobs/"+url.PathEscape(id), nil, "", false, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// People finds people by a part of their name. Needs the admin token.
+func (c *Client) People(ctx context.Context, query string) ([]api.Person, error) {
+	var out api.PersonList
+	if err := c.do(ctx, http.MethodGet, "admin/people?q="+url.QueryEscape(query), nil, "", true, &out); err != nil {
+		return nil, err
+	}
+	return out.People, nil
+}
+
+// SetHidden hides a person from searches, or shows them again. Needs the
+// admin token.
+func (c *Client) SetHidden(ctx context.Context, personID int64, hidden bool) error {
+	body, err := json.Marshal(api.PersonUpdate{Hidden: &hidden})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPatch, personPath(personID), bytes.NewReader(body), "application/json", true, nil)
+}
+
+// PurgePerson deletes a person and their images. Needs the admin token.
+func (c *Client) PurgePerson(ctx context.Context, personID int64) error {
+	return c.do(ctx, http.MethodDelete, personPath(personID), nil, "", true, nil)
+}
+
+// CheckCanaries re-embeds the canaries, reporting those whose drift is over
+// threshold. Needs the admin token.
+func (c *Client) CheckCanaries(ctx context.Context, threshold float64) (*api.CanaryCheck, error) {
+	var out api.CanaryCheck
+	path := "admin/canaries/check?threshold=" + strconv.FormatFloat(threshold, 'g', -1, 64)
+	if err := c.do(ctx, http.MethodPost, path, nil, "", true, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// do sends a request to path, relative to /api/v1, and decodes the response
+// into out unless it's nil.
+func (c *Client) do(ctx context.Context, method string, path string, body io.Reader, contentType string, admin bool, out any) error {
+	target, err := c.baseURL.Parse(path)
+	if err != nil {
+		return fmt.Errorf("client: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return fmt.Errorf("client: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if admin {
+		req.Header.Set("Authorization", "Bearer "+c.AdminToken)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("client: %w", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode >= 300 {
+		return readError(response)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+		return fmt.Errorf("client: decode response: %w", err)
+	}
+	return nil
+}
+
+func readError(response *http.Response) error {
+	var body api.ErrorResponse
+	data, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err := json.Unmarshal(data, &body); err != nil || body.Error.Code == "" {
+		// Not from the server itself, e.g. a proxy
+		body.Error = api.ErrorDetail{Code: "http_error", Message: http.StatusText(response.StatusCode)}
+	}
+	return &Error{StatusCode: response.StatusCode, Detail: body.Error}
+}
+
+func buildForm(categoryIDs []int64, fields func(*multipart.Writer) error) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	for _, id := range categoryIDs {
+		if err := form.WriteField("category_id", strconv.FormatInt(id, 10)); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := fields(form); err != nil {
+		return nil, "", err
+	}
+	if err := form.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, form.FormDataContentType(), nil
+}
+
+func imageFields(images []Image) func(*multipart.Writer) error {
+	return func(form *multipart.Writer) error {
+		if len(images) == 0 {
+			return errors.New("client: no images")
+		}
+		for _, image := range images {
+			if err := writeFile(form, "images", image.Name, image.Bytes); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func writeFile(form *multipart.Writer, field string, filename string, data []byte) error {
+	part, err := form.CreateFormFile(field, filename)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(data)
+	return err
+}
+
+func personPath(personID int64) string {
+	return "admin/people/" + strconv.FormatInt(personID, 10)
+}
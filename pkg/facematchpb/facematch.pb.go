@@ -0,0 +1,814 @@
+// The gRPC interface of the face-match server. It mirrors /api/v1: fields
+// are only ever added, and failed calls carry a google.rpc.ErrorInfo whose
+// reason is the same stable code the HTTP API returns, e.g. "no_face".
+//
+// Regenerate the Go code with scripts/generate-proto.sh.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: facematch.proto
+
+package facematchpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SearchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Query:
+	//
+	//	*SearchRequest_Image
+	//	*SearchRequest_ImageId
+	//	*SearchRequest_PersonId
+	Query isSearchRequest_Query `protobuf_oneof:"query"`
+	// Categories to search; all when empty.
+	CategoryIds   []int64 `protobuf:"varint,4,rep,packed,name=category_ids,json=categoryIds,proto3" json:"category_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_facematch_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_facematch_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_facematch_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SearchRequest) GetQuery() isSearchRequest_Query {
+	if x != nil {
+		return x.Query
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetImage() []byte {
+	if x != nil {
+		if x, ok := x.Query.(*SearchRequest_Image); ok {
+			return x.Image
+		}
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetImageId() int64 {
+	if x != nil {
+		if x, ok := x.Query.(*SearchRequest_ImageId); ok {
+			return x.ImageId
+		}
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetPersonId() int64 {
+	if x != nil {
+		if x, ok := x.Query.(*SearchRequest_PersonId); ok {
+			return x.PersonId
+		}
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetCategoryIds() []int64 {
+	if x != nil {
+		return x.CategoryIds
+	}
+	return nil
+}
+
+type isSearchRequest_Query interface {
+	isSearchRequest_Query()
+}
+
+type SearchRequest_Image struct {
+	Image []byte `protobuf:"bytes,1,opt,name=image,proto3,oneof"`
+}
+
+type SearchRequest_ImageId struct {
+	// Excludes the image's person from the matches.
+	ImageId int64 `protobuf:"varint,2,opt,name=image_id,json=imageId,proto3,oneof"`
+}
+
+type SearchRequest_PersonId struct {
+	// Excludes the person from the matches.
+	PersonId int64 `protobuf:"varint,3,opt,name=person_id,json=personId,proto3,oneof"`
+}
+
+func (*SearchRequest_Image) isSearchRequest_Query() {}
+
+func (*SearchRequest_ImageId) isSearchRequest_Query() {}
+
+func (*SearchRequest_PersonId) isSearchRequest_Query() {}
+
+// Match is a person whose enrolled image resembles the query.
+type Match struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	ImageId           int64                  `protobuf:"varint,1,opt,name=image_id,json=imageId,proto3" json:"image_id,omitempty"`
+	PersonId          int64                  `protobuf:"varint,2,opt,name=person_id,json=personId,proto3" json:"person_id,omitempty"`
+	CategoryId        int64                  `protobuf:"varint,3,opt,name=category_id,json=categoryId,proto3" json:"category_id,omitempty"`
+	DisplayName       string                 `protobuf:"bytes,4,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	DisambiguationTag string                 `protobuf:"bytes,5,opt,name=disambiguation_tag,json=disambiguationTag,proto3" json:"disambiguation_tag,omitempty"`
+	// Cosine similarity, higher is closer.
+	Similarity    float32 `protobuf:"fixed32,6,opt,name=similarity,proto3" json:"similarity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Match) Reset() {
+	*x = Match{}
+	mi := &file_facematch_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Match) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Match) ProtoMessage() {}
+
+func (x *Match) ProtoReflect() protoreflect.Message {
+	mi := &file_facematch_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Match.ProtoReflect.Descriptor instead.
+func (*Match) Descriptor() ([]byte, []int) {
+	return file_facematch_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Match) GetImageId() int64 {
+	if x != nil {
+		return x.ImageId
+	}
+	return 0
+}
+
+func (x *Match) GetPersonId() int64 {
+	if x != nil {
+		return x.PersonId
+	}
+	return 0
+}
+
+func (x *Match) GetCategoryId() int64 {
+	if x != nil {
+		return x.CategoryId
+	}
+	return 0
+}
+
+func (x *Match) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *Match) GetDisambiguationTag() string {
+	if x != nil {
+		return x.DisambiguationTag
+	}
+	return ""
+}
+
+func (x *Match) GetSimilarity() float32 {
+	if x != nil {
+		return x.Similarity
+	}
+	return 0
+}
+
+type SearchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Matches       []*Match               `protobuf:"bytes,1,rep,name=matches,proto3" json:"matches,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_facematch_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_facematch_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_facematch_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SearchResponse) GetMatches() []*Match {
+	if x != nil {
+		return x.Matches
+	}
+	return nil
+}
+
+type SearchBatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identifies the image's result. Repeated names get a numeric suffix.
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Image []byte `protobuf:"bytes,2,opt,name=image,proto3" json:"image,omitempty"`
+	// Only read from the first message.
+	CategoryIds   []int64 `protobuf:"varint,3,rep,packed,name=category_ids,json=categoryIds,proto3" json:"category_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchBatchRequest) Reset() {
+	*x = SearchBatchRequest{}
+	mi := &file_facematch_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchBatchRequest) ProtoMessage() {}
+
+func (x *SearchBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_facematch_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchBatchRequest.ProtoReflect.Descriptor instead.
+func (*SearchBatchRequest) Descriptor() ([]byte, []int) {
+	return file_facematch_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SearchBatchRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SearchBatchRequest) GetImage() []byte {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *SearchBatchRequest) GetCategoryIds() []int64 {
+	if x != nil {
+		return x.CategoryIds
+	}
+	return nil
+}
+
+type BatchResult struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Matches []*Match               `protobuf:"bytes,1,rep,name=matches,proto3" json:"matches,omitempty"`
+	// The error code of why the image couldn't be searched, instead of
+	// matches, e.g. no_face. Codes are those of the HTTP API.
+	Error         string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchResult) Reset() {
+	*x = BatchResult{}
+	mi := &file_facematch_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchResult) ProtoMessage() {}
+
+func (x *BatchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_facematch_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchResult.ProtoReflect.Descriptor instead.
+func (*BatchResult) Descriptor() ([]byte, []int) {
+	return file_facematch_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchResult) GetMatches() []*Match {
+	if x != nil {
+		return x.Matches
+	}
+	return nil
+}
+
+func (x *BatchResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type SearchBatchResponse struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Results       map[string]*BatchResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchBatchResponse) Reset() {
+	*x = SearchBatchResponse{}
+	mi := &file_facematch_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchBatchResponse) ProtoMessage() {}
+
+func (x *SearchBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_facematch_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchBatchResponse.ProtoReflect.Descriptor instead.
+func (*SearchBatchResponse) Descriptor() ([]byte, []int) {
+	return file_facematch_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SearchBatchResponse) GetResults() map[string]*BatchResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type EnrollRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The category's name.
+	Category          string `protobuf:"bytes,1,opt,name=category,proto3" json:"category,omitempty"`
+	DisplayName       string `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	DisambiguationTag string `protobuf:"bytes,3,opt,name=disambiguation_tag,json=disambiguationTag,proto3" json:"disambiguation_tag,omitempty"`
+	Image             []byte `protobuf:"bytes,4,opt,name=image,proto3" json:"image,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *EnrollRequest) Reset() {
+	*x = EnrollRequest{}
+	mi := &file_facematch_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnrollRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnrollRequest) ProtoMessage() {}
+
+func (x *EnrollRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_facematch_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnrollRequest.ProtoReflect.Descriptor instead.
+func (*EnrollRequest) Descriptor() ([]byte, []int) {
+	return file_facematch_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *EnrollRequest) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *EnrollRequest) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *EnrollRequest) GetDisambiguationTag() string {
+	if x != nil {
+		return x.DisambiguationTag
+	}
+	return ""
+}
+
+func (x *EnrollRequest) GetImage() []byte {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+type EnrollResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PersonId      int64                  `protobuf:"varint,1,opt,name=person_id,json=personId,proto3" json:"person_id,omitempty"`
+	ImageId       int64                  `protobuf:"varint,2,opt,name=image_id,json=imageId,proto3" json:"image_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnrollResponse) Reset() {
+	*x = EnrollResponse{}
+	mi := &file_facematch_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnrollResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnrollResponse) ProtoMessage() {}
+
+func (x *EnrollResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_facematch_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnrollResponse.ProtoReflect.Descriptor instead.
+func (*EnrollResponse) Descriptor() ([]byte, []int) {
+	return file_facematch_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *EnrollResponse) GetPersonId() int64 {
+	if x != nil {
+		return x.PersonId
+	}
+	return 0
+}
+
+func (x *EnrollResponse) GetImageId() int64 {
+	if x != nil {
+		return x.ImageId
+	}
+	return 0
+}
+
+type ListCategoriesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCategoriesRequest) Reset() {
+	*x = ListCategoriesRequest{}
+	mi := &file_facematch_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCategoriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCategoriesRequest) ProtoMessage() {}
+
+func (x *ListCategoriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_facematch_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCategoriesRequest.ProtoReflect.Descriptor instead.
+func (*ListCategoriesRequest) Descriptor() ([]byte, []int) {
+	return file_facematch_proto_rawDescGZIP(), []int{8}
+}
+
+type Category struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Category) Reset() {
+	*x = Category{}
+	mi := &file_facematch_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Category) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Category) ProtoMessage() {}
+
+func (x *Category) ProtoReflect() protoreflect.Message {
+	mi := &file_facematch_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Category.ProtoReflect.Descriptor instead.
+func (*Category) Descriptor() ([]byte, []int) {
+	return file_facematch_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Category) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Category) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type ListCategoriesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Categories    []*Category            `protobuf:"bytes,1,rep,name=categories,proto3" json:"categories,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCategoriesResponse) Reset() {
+	*x = ListCategoriesResponse{}
+	mi := &file_facematch_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCategoriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCategoriesResponse) ProtoMessage() {}
+
+func (x *ListCategoriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_facematch_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCategoriesResponse.ProtoReflect.Descriptor instead.
+func (*ListCategoriesResponse) Descriptor() ([]byte, []int) {
+	return file_facematch_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListCategoriesResponse) GetCategories() []*Category {
+	if x != nil {
+		return x.Categories
+	}
+	return nil
+}
+
+var File_facematch_proto protoreflect.FileDescriptor
+
+const file_facematch_proto_rawDesc = "" +
+	"\n" +
+	"\x0ffacematch.proto\x12\ffacematch.v1\"\x8f\x01\n" +
+	"\rSearchRequest\x12\x16\n" +
+	"\x05image\x18\x01 \x01(\fH\x00R\x05image\x12\x1b\n" +
+	"\bimage_id\x18\x02 \x01(\x03H\x00R\aimageId\x12\x1d\n" +
+	"\tperson_id\x18\x03 \x01(\x03H\x00R\bpersonId\x12!\n" +
+	"\fcategory_ids\x18\x04 \x03(\x03R\vcategoryIdsB\a\n" +
+	"\x05query\"\xd2\x01\n" +
+	"\x05Match\x12\x19\n" +
+	"\bimage_id\x18\x01 \x01(\x03R\aimageId\x12\x1b\n" +
+	"\tperson_id\x18\x02 \x01(\x03R\bpersonId\x12\x1f\n" +
+	"\vcategory_id\x18\x03 \x01(\x03R\n" +
+	"categoryId\x12!\n" +
+	"\fdisplay_name\x18\x04 \x01(\tR\vdisplayName\x12-\n" +
+	"\x12disambiguation_tag\x18\x05 \x01(\tR\x11disambiguationTag\x12\x1e\n" +
+	"\n" +
+	"similarity\x18\x06 \x01(\x02R\n" +
+	"similarity\"?\n" +
+	"\x0eSearchResponse\x12-\n" +
+	"\amatches\x18\x01 \x03(\v2\x13.facematch.v1.MatchR\amatches\"a\n" +
+	"\x12SearchBatchRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05image\x18\x02 \x01(\fR\x05image\x12!\n" +
+	"\fcategory_ids\x18\x03 \x03(\x03R\vcategoryIds\"R\n" +
+	"\vBatchResult\x12-\n" +
+	"\amatches\x18\x01 \x03(\v2\x13.facematch.v1.MatchR\amatches\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\xb6\x01\n" +
+	"\x13SearchBatchResponse\x12H\n" +
+	"\aresults\x18\x01 \x03(\v2..facematch.v1.SearchBatchResponse.ResultsEntryR\aresults\x1aU\n" +
+	"\fResultsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12/\n" +
+	"\x05value\x18\x02 \x01(\v2\x19.facematch.v1.BatchResultR\x05value:\x028\x01\"\x93\x01\n" +
+	"\rEnrollRequest\x12\x1a\n" +
+	"\bcategory\x18\x01 \x01(\tR\bcategory\x12!\n" +
+	"\fdisplay_name\x18\x02 \x01(\tR\vdisplayName\x12-\n" +
+	"\x12disambiguation_tag\x18\x03 \x01(\tR\x11disambiguationTag\x12\x14\n" +
+	"\x05image\x18\x04 \x01(\fR\x05image\"H\n" +
+	"\x0eEnrollResponse\x12\x1b\n" +
+	"\tperson_id\x18\x01 \x01(\x03R\bpersonId\x12\x19\n" +
+	"\bimage_id\x18\x02 \x01(\x03R\aimageId\"\x17\n" +
+	"\x15ListCategoriesRequest\".\n" +
+	"\bCategory\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"P\n" +
+	"\x16ListCategoriesResponse\x126\n" +
+	"\n" +
+	"categories\x18\x01 \x03(\v2\x16.facematch.v1.CategoryR\n" +
+	"categories2\xc8\x02\n" +
+	"\tFaceMatch\x12C\n" +
+	"\x06Search\x12\x1b.facematch.v1.SearchRequest\x1a\x1c.facematch.v1.SearchResponse\x12T\n" +
+	"\vSearchBatch\x12 .facematch.v1.SearchBatchRequest\x1a!.facematch.v1.SearchBatchResponse(\x01\x12C\n" +
+	"\x06Enroll\x12\x1b.facematch.v1.EnrollRequest\x1a\x1c.facematch.v1.EnrollResponse\x12[\n" +
+	"\x0eListCategories\x12#.facematch.v1.ListCategoriesRequest\x1a$.facematch.v1.ListCategoriesResponseB'Z%github.com/face-match/pkg/facematchpbb\x06proto3"
+
+var (
+	file_facematch_proto_rawDescOnce sync.Once
+	file_facematch_proto_rawDescData []byte
+)
+
+func file_facematch_proto_rawDescGZIP() []byte {
+	file_facematch_proto_rawDescOnce.Do(func() {
+		file_facematch_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_facematch_proto_rawDesc), len(file_facematch_proto_rawDesc)))
+	})
+	return file_facematch_proto_rawDescData
+}
+
+var file_facematch_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_facematch_proto_goTypes = []any{
+	(*SearchRequest)(nil),          // 0: facematch.v1.SearchRequest
+	(*Match)(nil),                  // 1: facematch.v1.Match
+	(*SearchResponse)(nil),         // 2: facematch.v1.SearchResponse
+	(*SearchBatchRequest)(nil),     // 3: facematch.v1.SearchBatchRequest
+	(*BatchResult)(nil),            // 4: facematch.v1.BatchResult
+	(*SearchBatchResponse)(nil),    // 5: facematch.v1.SearchBatchResponse
+	(*EnrollRequest)(nil),          // 6: facematch.v1.EnrollRequest
+	(*EnrollResponse)(nil),         // 7: facematch.v1.EnrollResponse
+	(*ListCategoriesRequest)(nil),  // 8: facematch.v1.ListCategoriesRequest
+	(*Category)(nil),               // 9: facematch.v1.Category
+	(*ListCategoriesResponse)(nil), // 10: facematch.v1.ListCategoriesResponse
+	nil,                            // 11: facematch.v1.SearchBatchResponse.ResultsEntry
+}
+var file_facematch_proto_depIdxs = []int32{
+	1,  // 0: facematch.v1.SearchResponse.matches:type_name -> facematch.v1.Match
+	1,  // 1: facematch.v1.BatchResult.matches:type_name -> facematch.v1.Match
+	11, // 2: facematch.v1.SearchBatchResponse.results:type_name -> facematch.v1.SearchBatchResponse.ResultsEntry
+	9,  // 3: facematch.v1.ListCategoriesResponse.categories:type_name -> facematch.v1.Category
+	4,  // 4: facematch.v1.SearchBatchResponse.ResultsEntry.value:type_name -> facematch.v1.BatchResult
+	0,  // 5: facematch.v1.FaceMatch.Search:input_type -> facematch.v1.SearchRequest
+	3,  // 6: facematch.v1.FaceMatch.SearchBatch:input_type -> facematch.v1.SearchBatchRequest
+	6,  // 7: facematch.v1.FaceMatch.Enroll:input_type -> facematch.v1.EnrollRequest
+	8,  // 8: facematch.v1.FaceMatch.ListCategories:input_type -> facematch.v1.ListCategoriesRequest
+	2,  // 9: facematch.v1.FaceMatch.Search:output_type -> facematch.v1.SearchResponse
+	5,  // 10: facematch.v1.FaceMatch.SearchBatch:output_type -> facematch.v1.SearchBatchResponse
+	7,  // 11: facematch.v1.FaceMatch.Enroll:output_type -> facematch.v1.EnrollResponse
+	10, // 12: facematch.v1.FaceMatch.ListCategories:output_type -> facematch.v1.ListCategoriesResponse
+	9,  // [9:13] is the sub-list for method output_type
+	5,  // [5:9] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_facematch_proto_init() }
+func file_facematch_proto_init() {
+	if File_facematch_proto != nil {
+		return
+	}
+	file_facematch_proto_msgTypes[0].OneofWrappers = []any{
+		(*SearchRequest_Image)(nil),
+		(*SearchRequest_ImageId)(nil),
+		(*SearchRequest_PersonId)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_facematch_proto_rawDesc), len(file_facematch_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_facematch_proto_goTypes,
+		DependencyIndexes: file_facematch_proto_depIdxs,
+		MessageInfos:      file_facematch_proto_msgTypes,
+	}.Build()
+	File_facematch_proto = out.File
+	file_facematch_proto_goTypes = nil
+	file_facematch_proto_depIdxs = nil
+}
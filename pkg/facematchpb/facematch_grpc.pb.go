@@ -0,0 +1,247 @@
+// The gRPC interface of the face-match server. It mirrors /api/v1: fields
+// are only ever added, and failed calls carry a google.rpc.ErrorInfo whose
+// reason is the same stable code the HTTP API returns, e.g. "no_face".
+//
+// Regenerate the Go code with scripts/generate-proto.sh.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: facematch.proto
+
+package facematchpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	FaceMatch_Search_FullMethodName         = "/facematch.v1.FaceMatch/Search"
+	FaceMatch_SearchBatch_FullMethodName    = "/facematch.v1.FaceMatch/SearchBatch"
+	FaceMatch_Enroll_FullMethodName         = "/facematch.v1.FaceMatch/Enroll"
+	FaceMatch_ListCategories_FullMethodName = "/facematch.v1.FaceMatch/ListCategories"
+)
+
+// FaceMatchClient is the client API for FaceMatch service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FaceMatchClient interface {
+	// Searches the largest face of an image, or the embeddings of a stored
+	// image or person.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// Searches a stream of images. The categories are taken from the first
+	// message. A failure for one image is reported in its result.
+	SearchBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SearchBatchRequest, SearchBatchResponse], error)
+	// Adds an image of a person to a category. Needs the admin token as
+	// "authorization: Bearer <token>" metadata.
+	Enroll(ctx context.Context, in *EnrollRequest, opts ...grpc.CallOption) (*EnrollResponse, error)
+	ListCategories(ctx context.Context, in *ListCategoriesRequest, opts ...grpc.CallOption) (*ListCategoriesResponse, error)
+}
+
+type faceMatchClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFaceMatchClient(cc grpc.ClientConnInterface) FaceMatchClient {
+	return &faceMatchClient{cc}
+}
+
+func (c *faceMatchClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, FaceMatch_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *faceMatchClient) SearchBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SearchBatchRequest, SearchBatchResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FaceMatch_ServiceDesc.Streams[0], FaceMatch_SearchBatch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SearchBatchRequest, SearchBatchResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FaceMatch_SearchBatchClient = grpc.ClientStreamingClient[SearchBatchRequest, SearchBatchResponse]
+
+func (c *faceMatchClient) Enroll(ctx context.Context, in *EnrollRequest, opts ...grpc.CallOption) (*EnrollResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EnrollResponse)
+	err := c.cc.Invoke(ctx, FaceMatch_Enroll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *faceMatchClient) ListCategories(ctx context.Context, in *ListCategoriesRequest, opts ...grpc.CallOption) (*ListCategoriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCategoriesResponse)
+	err := c.cc.Invoke(ctx, FaceMatch_ListCategories_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FaceMatchServer is the server API for FaceMatch service.
+// All implementations must embed UnimplementedFaceMatchServer
+// for forward compatibility.
+type FaceMatchServer interface {
+	// Searches the largest face of an image, or the embeddings of a stored
+	// image or person.
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// Searches a stream of images. The categories are taken from the first
+	// message. A failure for one image is reported in its result.
+	SearchBatch(grpc.ClientStreamingServer[SearchBatchRequest, SearchBatchResponse]) error
+	// Adds an image of a person to a category. Needs the admin token as
+	// "authorization: Bearer <token>" metadata.
+	Enroll(context.Context, *EnrollRequest) (*EnrollResponse, error)
+	ListCategories(context.Context, *ListCategoriesRequest) (*ListCategoriesResponse, error)
+	mustEmbedUnimplementedFaceMatchServer()
+}
+
+// UnimplementedFaceMatchServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedFaceMatchServer struct{}
+
+func (UnimplementedFaceMatchServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedFaceMatchServer) SearchBatch(grpc.ClientStreamingServer[SearchBatchRequest, SearchBatchResponse]) error {
+	return status.Error(codes.Unimplemented, "method SearchBatch not implemented")
+}
+func (UnimplementedFaceMatchServer) Enroll(context.Context, *EnrollRequest) (*EnrollResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Enroll not implemented")
+}
+func (UnimplementedFaceMatchServer) ListCategories(context.Context, *ListCategoriesRequest) (*ListCategoriesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListCategories not implemented")
+}
+func (UnimplementedFaceMatchServer) mustEmbedUnimplementedFaceMatchServer() {}
+func (UnimplementedFaceMatchServer) testEmbeddedByValue()                   {}
+
+// UnsafeFaceMatchServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FaceMatchServer will
+// result in compilation errors.
+type UnsafeFaceMatchServer interface {
+	mustEmbedUnimplementedFaceMatchServer()
+}
+
+func RegisterFaceMatchServer(s grpc.ServiceRegistrar, srv FaceMatchServer) {
+	// If the following call panics, it indicates UnimplementedFaceMatchServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&FaceMatch_ServiceDesc, srv)
+}
+
+func _FaceMatch_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FaceMatchServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FaceMatch_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FaceMatchServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FaceMatch_SearchBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FaceMatchServer).SearchBatch(&grpc.GenericServerStream[SearchBatchRequest, SearchBatchResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FaceMatch_SearchBatchServer = grpc.ClientStreamingServer[SearchBatchRequest, SearchBatchResponse]
+
+func _FaceMatch_Enroll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnrollRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FaceMatchServer).Enroll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FaceMatch_Enroll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FaceMatchServer).Enroll(ctx, req.(*EnrollRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FaceMatch_ListCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCategoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FaceMatchServer).ListCategories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FaceMatch_ListCategories_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FaceMatchServer).ListCategories(ctx, req.(*ListCategoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// FaceMatch_ServiceDesc is the grpc.ServiceDesc for FaceMatch service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FaceMatch_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "facematch.v1.FaceMatch",
+	HandlerType: (*FaceMatchServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Search",
+			Handler:    _FaceMatch_Search_Handler,
+		},
+		{
+			MethodName: "Enroll",
+			Handler:    _FaceMatch_Enroll_Handler,
+		},
+		{
+			MethodName: "ListCategories",
+			Handler:    _FaceMatch_ListCategories_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SearchBatch",
+			Handler:       _FaceMatch_SearchBatch_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "facematch.proto",
+}
@@ -7,6 +7,9 @@ go build -o bin/ingest ./cmd/ingest
 echo "Building server..."
 go build -o bin/server ./cmd/server
 
+echo "Building fake sidecar..."
+go build -o bin/fake-sidecar ./cmd/fake-sidecar
+
 echo "Building scrapers..."
 
 # Find all scraper main.go files
@@ -0,0 +1,17 @@
+#!/usr/bin/env bash
+set -e
+
+# Regenerates the gRPC code in pkg/facematchpb from facematch.proto. Needs
+# protoc, and the Go plugins at these versions:
+#
+#   go install google.golang.org/protobuf/cmd/protoc-gen-go@v1.36.12
+#   go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@v1.6.2
+
+dir=pkg/facematchpb
+
+protoc -I "$dir" \
+  --go_out="$dir" --go_opt=paths=source_relative \
+  --go-grpc_out="$dir" --go-grpc_opt=paths=source_relative \
+  facematch.proto
+
+echo "Updated $dir."
@@ -0,0 +1,6 @@
+#!/usr/bin/env bash
+set -e
+
+# Stands in for the AI sidecar on :8081 without Python or InsightFace.
+# Pass e.g. "-mode no-face" to see how the other programs handle bad input.
+go run ./cmd/fake-sidecar -addr :8081 "$@"
@@ -0,0 +1,65 @@
+#!/usr/bin/env bash
+set -e
+
+# Downloads the third-party files the web UI serves from web/static/vendor.
+# Bootstrap's bundle includes Popper.
+#
+# Every file must match its pinned Subresource Integrity hash; one that
+# doesn't is refused and the vendored copy is left alone. With --check, the
+# vendored files are verified without downloading.
+#
+# jQuery stays on 3.6.1, the version the UI is written and tested against.
+# Its hash is that of the vendored copy, Debian's libjs-jquery build of
+# 3.6.1; if the download differs, check it and update the hash by hand.
+
+BOOTSTRAP_VERSION=5.3.8
+JQUERY_VERSION=3.6.1
+
+dir=web/static/vendor
+
+# name, URL and integrity of each file
+files=(
+	"bootstrap.min.css https://cdn.jsdelivr.net/npm/bootstrap@$BOOTSTRAP_VERSION/dist/css/bootstrap.min.css sha384-sRIl4kxILFvY47J16cr9ZwB07vP4J8+LH7qKQnuqkuIAvNWLzeN8tE5YBujZqJLB"
+	"bootstrap.bundle.min.js https://cdn.jsdelivr.net/npm/bootstrap@$BOOTSTRAP_VERSION/dist/js/bootstrap.bundle.min.js sha384-FKyoEForCGlyvwx9Hj09JcYn3nv7wiPVlz7YYwJrWVcXK/BmnVDxM+D2scQbITxI"
+	"jquery.min.js https://code.jquery.com/jquery-$JQUERY_VERSION.min.js sha256-AzeKcltot5FBnYP0fxD/fKWBnH2dHa26nt0m7yzliP0="
+)
+
+# integrity prints a file's hash in SRI form, with the algorithm of expected
+integrity() {
+	local algorithm=${2%%-*}
+	echo "$algorithm-$(openssl dgst "-$algorithm" -binary "$1" | openssl base64 -A)"
+}
+
+if [ "$1" = "--check" ]; then
+	failed=0
+	for entry in "${files[@]}"; do
+		read -r name url expected <<<"$entry"
+		if [ ! -f "$dir/$name" ] || [ "$(integrity "$dir/$name" "$expected")" != "$expected" ]; then
+			echo "$dir/$name does not match $url ($expected)" >&2
+			failed=1
+		fi
+	done
+	exit $failed
+fi
+
+mkdir -p "$dir"
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+for entry in "${files[@]}"; do
+	read -r name url expected <<<"$entry"
+	curl -fsSL -o "$tmp/$name" "$url"
+	actual=$(integrity "$tmp/$name" "$expected")
+	if [ "$actual" != "$expected" ]; then
+		echo "$url has integrity $actual, expected $expected" >&2
+		exit 1
+	fi
+done
+
+# Only replaced once every download checked out
+for entry in "${files[@]}"; do
+	read -r name _ <<<"$entry"
+	mv "$tmp/$name" "$dir/$name"
+done
+
+echo "Updated $dir; rebuild the server to embed the new files."
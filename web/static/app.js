@@ -6,13 +6,13 @@ function escape(unsafe) {
 
 function fetchCategories($container) {
     $.ajax({
-        url: "/api/categories",
-        success: function (results) {
-            results.forEach((result, _1, _2) => {
-                const id = result.ID, name = result.DisplayName;
+        url: "/api/v1/categories",
+        success: function (body) {
+            body.categories.forEach((category, _1, _2) => {
+                const id = category.id, name = category.name;
                 categories[id] = name;
                 const containerEl = $(`<div class="form-check form-check-inline"/>`)
-                    .append($(`<input class="form-check-input" type="checkbox" value="${id}" id="category-${id}" name="categories[]" checked/>`))
+                    .append($(`<input class="form-check-input" type="checkbox" value="${id}" id="category-${id}" name="category_id" checked/>`))
                     .append($(`<label class="form-check-label" for="category-${id}">${escape(name)}</label>`));
                 $container.append(containerEl);
             })
@@ -39,26 +39,38 @@ function wireSubmit($form, $resultsContainer) {
         const data = new FormData($form[0]);
         $.ajax({
             method: "POST",
-            url: "/api/search",
+            url: "/api/v1/search",
             data: data,
             processData: false,
             contentType: false,
-            success: function (results) {
-                results.forEach((result, _1, _2) => {
-                    const name = result.DisplayName, tag = result.DisambiguationTag, score = result.SimilarityScore;
+            success: function (body) {
+                body.matches.forEach((match, _1, _2) => {
+                    const name = match.display_name, tag = match.disambiguation_tag, score = match.similarity;
                     const display = !!tag ? `${name} (${tag})` : name;
-                    const category = categories[result.CategoryID];
+                    const category = categories[match.category_id];
                     const containerEl = $(`<div class="search-result"/>`)
                         .append($(`<h3 class="h5"><a href="https://www.google.com/search?q=${escape(display)}+${escape(category)}">${escape(display)}</a</h3>`))
                         .append($(`<div>Category: ${category}</div>`))
                         .append($(`<div>Similarity Score: ${score.toFixed(2)}</div>`))
                     $resultsContainer.append(containerEl);
                 })
+            },
+            error: function (xhr) {
+                const $alert = $(`<div class="alert alert-warning" role="alert"/>`).text(describeError(xhr));
+                $resultsContainer.append($alert);
             }
         })
     })
 }
 
+function describeError(xhr) {
+    const body = xhr.responseJSON;
+    if (body && body.error && body.error.message) {
+        return body.error.message;
+    }
+    return "The search failed. Please try again.";
+}
+
 $(function () {
     fetchCategories($("#categories-container"));
     wireImagePreview($("#image-input"), $("#image-preview"))